@@ -0,0 +1,77 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"io"
+	pth "path"
+	"strings"
+)
+
+// App is an OpenCPU app, an R package installed from GitHub and served
+// under the apps endpoint.
+type App struct {
+	Owner string
+	Name  string
+}
+
+// Package returns the R package of the app. The package can be used to
+// list and call the app's functions and to list its www assets.
+func (a App) Package() Package {
+	return Package{Library: Library(pth.Join("apps", a.Owner)), Name: a.Name}
+}
+
+// Function returns the named exported function of the app.
+func (a App) Function(name string) Function {
+	return Function{Package: a.Package(), Name: name}
+}
+
+// Apps returns the apps installed on the server. An *UnsupportedError is
+// returned if the server does not provide the apps endpoint.
+func (s *Session) Apps(ctx context.Context) ([]App, error) {
+	err := s.require(ctx, CapApps)
+	if err != nil {
+		return nil, err
+	}
+	names, err := s.list(ctx, "apps")
+	if err != nil {
+		return nil, err
+	}
+	var apps []App
+	for _, n := range names {
+		n = strings.Trim(n, "/")
+		if owner, app := pth.Split(n); owner != "" {
+			apps = append(apps, App{Owner: strings.TrimSuffix(owner, "/"), Name: app})
+			continue
+		}
+		// The listing holds owners, so list their apps.
+		owned, err := s.list(ctx, pth.Join("apps", n))
+		if err != nil {
+			return nil, err
+		}
+		for _, app := range owned {
+			apps = append(apps, App{Owner: n, Name: strings.Trim(app, "/")})
+		}
+	}
+	return apps, nil
+}
+
+// AppDescription returns the parsed DESCRIPTION file of the app, holding
+// its version and other metadata. An *UnsupportedError is returned if
+// the server does not provide the apps endpoint.
+func (s *Session) AppDescription(ctx context.Context, a App) (*PackageDescription, error) {
+	err := s.require(ctx, CapApps)
+	if err != nil {
+		return nil, err
+	}
+	return s.Description(ctx, a.Package())
+}
+
+// OpenAsset returns a reader for the contents of the www asset. The
+// returned reader must be closed after use.
+func (s *Session) OpenAsset(ctx context.Context, a Asset) (io.ReadCloser, error) {
+	return s.open(ctx, a.Package.path("www", a.Path), nil)
+}
@@ -0,0 +1,83 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestApps(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ocpu-Version", "2.2.9")
+		switch r.URL.Path {
+		case "/ocpu/":
+		case "/ocpu/apps/":
+			w.Write([]byte("rwebapps/\nopencpu/markdownapp\n"))
+		case "/ocpu/apps/rwebapps/":
+			w.Write([]byte("nabel\nstockapp\n"))
+		case "/ocpu/apps/rwebapps/stockapp/DESCRIPTION":
+			w.Write([]byte("Package: stockapp\nVersion: 1.2.0\n"))
+		case "/ocpu/apps/rwebapps/stockapp/www/":
+			w.Write([]byte("index.html\n"))
+		case "/ocpu/apps/rwebapps/stockapp/www/index.html":
+			w.Write([]byte("<html></html>"))
+		case "/ocpu/apps/rwebapps/stockapp/R/smoothplot/json":
+			w.Write([]byte("[1]"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	s := testSession(t, srv.URL)
+	ctx := context.Background()
+
+	apps, err := s.Apps(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error listing apps: %v", err)
+	}
+	want := []App{{"rwebapps", "nabel"}, {"rwebapps", "stockapp"}, {"opencpu", "markdownapp"}}
+	if !reflect.DeepEqual(apps, want) {
+		t.Errorf("unexpected apps: got:%v want:%v", apps, want)
+	}
+
+	stockapp := App{Owner: "rwebapps", Name: "stockapp"}
+	d, err := s.AppDescription(ctx, stockapp)
+	if err != nil {
+		t.Fatalf("unexpected error getting description: %v", err)
+	}
+	if d.Version != "1.2.0" {
+		t.Errorf("unexpected version: got:%q want:%q", d.Version, "1.2.0")
+	}
+
+	assets, err := s.Assets(ctx, stockapp.Package())
+	if err != nil {
+		t.Fatalf("unexpected error listing assets: %v", err)
+	}
+	if len(assets) != 1 {
+		t.Fatalf("unexpected number of assets: got:%d want:1", len(assets))
+	}
+	r, err := s.OpenAsset(ctx, assets[0])
+	if err != nil {
+		t.Fatalf("unexpected error opening asset: %v", err)
+	}
+	b, _ := ioutil.ReadAll(r)
+	r.Close()
+	if string(b) != "<html></html>" {
+		t.Errorf("unexpected asset: got:%q", b)
+	}
+
+	v, err := s.Invoke(ctx, stockapp.Function("smoothplot"), nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error calling app function: %v", err)
+	}
+	if string(v) != "[1]" {
+		t.Errorf("unexpected value: got:%s", v)
+	}
+}
@@ -10,26 +10,35 @@
 package arrgh
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net"
 	"net/http"
 	"net/url"
 	"os/exec"
 	pth "path"
-	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"time"
 )
 
 // Session holds OpenCPU session connection information.
 type Session struct {
-	cmd  *exec.Cmd
-	host *url.URL
-	root string
+	cmd    *exec.Cmd
+	host   *url.URL
+	root   string
+	client *http.Client
+
+	// shared is the reference to the shared
+	// local server used by the session, if any.
+	shared *sharedRef
+
+	mu      sync.Mutex
+	version string
+	formats map[Capability]bool
 }
 
 // NewLocalSession starts an R instance using the executable in the given
@@ -41,6 +50,13 @@ type Session struct {
 //
 // It is important that Close() be called on sessions returned by NewLocalSession.
 func NewLocalSession(path, root string, port int, timeout time.Duration, log io.Writer) (*Session, error) {
+	return newLocalSession(context.Background(), path, root, port, timeout, log)
+}
+
+// newLocalSession is NewLocalSession, except that it abandons the start
+// of the server and returns the error of ctx if ctx is done before the
+// server can be reached.
+func newLocalSession(ctx context.Context, path, root string, port int, timeout time.Duration, log io.Writer) (*Session, error) {
 	var (
 		sess Session
 		err  error
@@ -92,7 +108,12 @@ if (parse_version(as.character(packageVersion("opencpu"))) < "2.0.0") {
 	start := time.Now()
 	u := sess.host.String()
 	for {
-		time.Sleep(time.Second)
+		select {
+		case <-ctx.Done():
+			sess.cmd.Process.Kill()
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+		}
 		_, err := http.Get(u)
 		if err == nil {
 			return &sess, nil
@@ -107,8 +128,14 @@ if (parse_version(as.character(packageVersion("opencpu"))) < "2.0.0") {
 func (s *Session) Root() string { return s.root }
 
 // Close shuts down a running local session, terminating the OpenCPU server
-// and the R session. It is a no-op on a remote session.
+// and the R session. It is a no-op on a remote session. Closing a session
+// returned by NewSharedLocalSession releases its reference to the shared
+// server, which is only terminated when no references remain.
 func (s *Session) Close() error {
+	if s.shared != nil && s.host != nil {
+		s.host = nil
+		return releaseShared(s.shared)
+	}
 	if s.cmd == nil || s.host == nil {
 		return nil
 	}
@@ -119,6 +146,13 @@ func (s *Session) Close() error {
 // NewRemoteSession connects to the OpenCPU server at the specified host. The
 // root of the OpenCPU API is set to "/ocpu" if it is left empty.
 func NewRemoteSession(host, root string, timeout time.Duration) (*Session, error) {
+	return NewRemoteSessionWithClient(host, root, timeout, nil)
+}
+
+// NewRemoteSessionWithClient is like NewRemoteSession but uses the provided
+// HTTP client for all requests made by the session, including the initial
+// connection test. If client is nil, http.DefaultClient is used.
+func NewRemoteSessionWithClient(host, root string, timeout time.Duration, client *http.Client) (*Session, error) {
 	var (
 		sess Session
 		err  error
@@ -132,13 +166,14 @@ func NewRemoteSession(host, root string, timeout time.Duration) (*Session, error
 	}
 	sess.host.Path = pth.Join(sess.host.Path, root)
 	sess.root = pth.Join("/", root)
+	sess.client = client
 
 	start := time.Now()
 	u := sess.host.String()
 	for {
-		time.Sleep(time.Second)
-		_, err := http.Get(u)
+		resp, err := sess.httpClient().Get(u)
 		if err == nil {
+			resp.Body.Close()
 			return &sess, nil
 		} else if timeout > 0 && time.Now().Sub(start) > timeout {
 			if err, ok := err.(net.Error); ok && err.Temporary() {
@@ -146,6 +181,7 @@ func NewRemoteSession(host, root string, timeout time.Duration) (*Session, error
 			}
 			return nil, err
 		}
+		time.Sleep(time.Second)
 	}
 }
 
@@ -153,15 +189,37 @@ func NewRemoteSession(host, root string, timeout time.Duration) (*Session, error
 // type using the POST method. The URL parameters specify additional POST parameters.
 // These parameters are interpreted by jsonlite.
 //
+// If query has a Size method returning a non-negative value, as the body returned
+// by StreamMultipart does, it is used as the content length of the request.
+//
 // See https://www.opencpu.org/api.html#api-methods and https://www.opencpu.org/api.html#api-arguments for details.
 func (s *Session) Post(path, content string, params url.Values, query io.Reader) (*http.Response, error) {
+	return s.PostContext(context.Background(), path, content, params, query)
+}
+
+// PostContext is like Post but uses the provided context for the request.
+// If the context was returned by WithProgress, the progress of the request
+// is reported.
+func (s *Session) PostContext(ctx context.Context, path, content string, params url.Values, query io.Reader) (*http.Response, error) {
 	if s.host == nil {
 		return nil, errors.New("arrgh: POST on closed session")
 	}
-	u := *s.host
-	u.Path = pth.Join(s.host.Path, path)
-	u.RawQuery = params.Encode()
-	return http.Post(u.String(), content, query)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url(path, params), query)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", content)
+	if b, ok := query.(sizer); ok {
+		if n := b.Size(); n >= 0 {
+			req.ContentLength = n
+		}
+	}
+	return s.do(req)
+}
+
+// sizer is a body that knows its length.
+type sizer interface {
+	Size() int64
 }
 
 // Get retrieves the given OpenCPU path using the GET method. The URL parameters specify
@@ -169,55 +227,56 @@ func (s *Session) Post(path, content string, params url.Values, query io.Reader)
 //
 // See https://www.opencpu.org/api.html#api-methods for details.
 func (s *Session) Get(path string, params url.Values) (*http.Response, error) {
+	return s.GetContext(context.Background(), path, params)
+}
+
+// GetContext is like Get but uses the provided context for the request.
+// If the context was returned by WithProgress, the progress of the request
+// is reported.
+func (s *Session) GetContext(ctx context.Context, path string, params url.Values) (*http.Response, error) {
 	if s.host == nil {
 		return nil, errors.New("arrgh: GET on closed session")
 	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url(path, params), nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.do(req)
+}
+
+// url returns the URL for the given OpenCPU path and parameters.
+// A trailing slash on path is retained since OpenCPU uses it to
+// indicate a directory listing.
+func (s *Session) url(path string, params url.Values) string {
 	u := *s.host
 	u.Path = pth.Join(s.host.Path, path)
+	if strings.HasSuffix(path, "/") {
+		u.Path += "/"
+	}
 	u.RawQuery = params.Encode()
-	return http.Get(u.String())
+	return u.String()
 }
 
-// Params is a collection of parameter names and values to be passed using Multipart.
-type Params map[string]string
-
-// NamedReader allows an io.Reader to be passed as a named data file object.
-type NamedReader interface {
-	io.Reader
-	Name() string
-}
-
-// Params is a collection of parameter names and file objects to be passed using Multipart.
-type Files map[string]NamedReader
-
-// Multipart constructs a MIME multipart body and associated content type from the
-// provided parameters and files.
-func Multipart(parameters Params, files Files) (content string, body io.Reader, err error) {
-	var buf bytes.Buffer
-	w := multipart.NewWriter(&buf)
-
-	for label, f := range files {
-		p, err := w.CreateFormFile(label, filepath.Base(f.Name()))
-		if err != nil {
-			return "", nil, err
-		}
-		_, err = io.Copy(p, f)
-		if err != nil {
-			return "", nil, err
-		}
+// httpClient returns the HTTP client used by the session.
+func (s *Session) httpClient() *http.Client {
+	if s.client == nil {
+		return http.DefaultClient
 	}
+	return s.client
+}
 
-	for k, v := range parameters {
-		err := w.WriteField(k, v)
-		if err != nil {
-			return "", nil, err
-		}
+// do sends the request, reporting progress if requested by
+// the request's context.
+func (s *Session) do(req *http.Request) (*http.Response, error) {
+	fn := progressFunc(req.Context())
+	if fn == nil {
+		return s.httpClient().Do(req)
 	}
-
-	err = w.Close()
+	p := newProgress(req, fn)
+	resp, err := s.httpClient().Do(req)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
-
-	return w.FormDataContentType(), &buf, nil
+	p.receive(resp)
+	return resp, nil
 }
@@ -0,0 +1,264 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/kortschak/arrgh"
+)
+
+// signature is an R function signature and its manual page title.
+type signature struct {
+	*arrgh.Signature
+	title string
+}
+
+// binding is a Go function wrapping an R function.
+type binding struct {
+	GoName string
+	RName  string
+	Title  string
+	Params []param
+	Dots   bool
+}
+
+// Args returns the Go parameter list of the binding following the
+// session parameter.
+func (b binding) Args() string {
+	var buf strings.Builder
+	for i, p := range b.Params {
+		buf.WriteString(", ")
+		buf.WriteString(p.GoName)
+		if i == len(b.Params)-1 || b.Params[i+1].Type() != p.Type() {
+			buf.WriteString(" ")
+			buf.WriteString(p.Type())
+		}
+	}
+	if b.Dots {
+		buf.WriteString(", dots map[string]interface{}")
+	}
+	return buf.String()
+}
+
+// Scalars returns whether any parameter of the binding has a scalar type.
+func (b binding) Scalars() bool {
+	for _, p := range b.Params {
+		if p.Scalar != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// param is a Go parameter corresponding to an R formal argument.
+type param struct {
+	GoName  string
+	RName   string
+	Default string
+
+	// Scalar is the Go type of the scalar R
+	// value of the default, if it has one.
+	Scalar string
+}
+
+// Type returns the Go type of the parameter. Parameters with a scalar
+// default are pointers to the scalar's type so that nil can select the
+// R default.
+func (p param) Type() string {
+	if p.Scalar == "" {
+		return "interface{}"
+	}
+	return "*" + p.Scalar
+}
+
+var bindingTemplate = template.Must(template.New("bindings").Parse(`// Code generated by arrgh-gen; DO NOT EDIT.
+
+// Package {{.GoPackage}} provides Go wrappers for the R package {{.Package.Name}}.
+package {{.GoPackage}}
+
+import (
+	"context"
+
+	"github.com/kortschak/arrgh"
+)
+
+var pkg = arrgh.Package{Library: {{printf "%q" .Package.Library}}, Name: {{printf "%q" .Package.Name}}}
+{{range .Bindings}}
+// {{.GoName}} calls the R function {{$.Package.Name}}::{{.RName}}.{{if .Title}}
+//
+// The R documentation for {{.RName}} is titled "{{.Title}}".{{end}}
+//
+// Arguments are encoded as JSON. A nil argument is not passed, so the R default
+// is used.{{if .Scalars}} Arguments with a logical, numeric, integer or character
+// default take a pointer to the corresponding Go type.{{end}}{{range .Params}}{{if .Default}}
+//  {{.RName}} = {{.Default}}{{end}}{{end}}{{if .Dots}}
+//
+// Additional named arguments are passed to "..." via dots.{{end}}
+func {{.GoName}}(ctx context.Context, s *arrgh.Session{{.Args}}) (arrgh.Value, error) {
+	args := map[string]interface{}{ {{- range .Params}}{{if not .Scalar}}
+		{{printf "%q" .RName}}: {{.GoName}},{{end}}{{end}}
+	}{{range .Params}}{{if .Scalar}}
+	if {{.GoName}} != nil {
+		args[{{printf "%q" .RName}}] = *{{.GoName}}
+	}{{end}}{{end}}{{if .Dots}}
+	for k, v := range dots {
+		args[k] = v
+	}{{end}}
+	return s.Invoke(ctx, arrgh.Function{Package: pkg, Name: {{printf "%q" .RName}}}, args, nil)
+}
+{{end}}`))
+
+// generate returns the formatted Go source for wrappers of the R functions
+// described by sigs in the Go package gopkg.
+func generate(gopkg string, p arrgh.Package, sigs []signature) ([]byte, error) {
+	var bindings []binding
+	seen := make(map[string]bool)
+	for _, sig := range sigs {
+		name := exportedName(sig.Function.Name)
+		if name == "" || seen[name] {
+			// Operators and names that collide with an
+			// earlier function are not wrapped.
+			continue
+		}
+		seen[name] = true
+
+		b := binding{
+			GoName: name,
+			RName:  sig.Function.Name,
+			Title:  strings.Join(strings.Fields(sig.title), " "),
+			Dots:   sig.Dots,
+		}
+		// The names used by the generated function body,
+		// including the predeclared identifiers it refers
+		// to, and its imports are reserved.
+		used := map[string]bool{
+			"ctx": true, "s": true, "args": true, "pkg": true, "dots": true, "k": true, "v": true,
+			"string": true, "interface": true, "nil": true,
+			"arrgh": true, "context": true,
+		}
+		for _, a := range sig.Args {
+			goName := paramName(a.Name)
+			for base, i := goName, 2; used[goName]; i++ {
+				goName = fmt.Sprintf("%s%d", base, i)
+			}
+			used[goName] = true
+			var def, scalar string
+			if a.HasDefault {
+				def = strings.Join(strings.Fields(a.Default), " ")
+				scalar = scalarType(def)
+			}
+			b.Params = append(b.Params, param{GoName: goName, RName: a.Name, Default: def, Scalar: scalar})
+		}
+		bindings = append(bindings, b)
+	}
+
+	var buf bytes.Buffer
+	err := bindingTemplate.Execute(&buf, struct {
+		GoPackage string
+		Package   arrgh.Package
+		Bindings  []binding
+	}{
+		GoPackage: gopkg,
+		Package:   p,
+		Bindings:  bindings,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+var (
+	stringLiteral  = `(?:"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*')`
+	choicesPattern = regexp.MustCompile(`^c\(\s*` + stringLiteral + `(?:\s*,\s*` + stringLiteral + `)*\s*\)$`)
+	stringPattern  = regexp.MustCompile(`^` + stringLiteral + `$`)
+	intPattern     = regexp.MustCompile(`^-?[0-9]+L$`)
+	numberPattern  = regexp.MustCompile(`^-?(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:[eE][-+]?[0-9]+)?$`)
+)
+
+// scalarType returns the Go type of the scalar R value of the deparsed
+// default expression def, or the empty string if def is not a logical,
+// numeric, integer or character literal. A vector of character literals,
+// as used by match.arg, is treated as a character scalar.
+func scalarType(def string) string {
+	switch {
+	case def == "TRUE" || def == "FALSE" || def == "T" || def == "F":
+		return "bool"
+	case intPattern.MatchString(def):
+		return "int"
+	case numberPattern.MatchString(def):
+		return "float64"
+	case stringPattern.MatchString(def), choicesPattern.MatchString(def):
+		return "string"
+	}
+	return ""
+}
+
+// words returns the alphanumeric words of an R name.
+func words(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// exportedName returns an exported Go name for the R name, or the
+// empty string if the R name is not a syntactic R name.
+func exportedName(name string) string {
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '_' {
+			return ""
+		}
+	}
+	w := words(name)
+	if len(w) == 0 || !unicode.IsLetter([]rune(w[0])[0]) {
+		return ""
+	}
+	for i, s := range w {
+		w[i] = upperFirst(s)
+	}
+	return strings.Join(w, "")
+}
+
+// paramName returns a Go parameter name for the R argument name.
+func paramName(name string) string {
+	w := words(name)
+	if len(w) == 0 {
+		return "arg"
+	}
+	for i := 1; i < len(w); i++ {
+		w[i] = upperFirst(w[i])
+	}
+	n := strings.Join(w, "")
+	if !unicode.IsLetter([]rune(n)[0]) {
+		n = "arg" + n
+	}
+	if token.Lookup(n).IsKeyword() {
+		n += "_"
+	}
+	return n
+}
+
+// packageName returns a Go package name for the R package name.
+func packageName(name string) string {
+	n := strings.ToLower(strings.Join(words(name), ""))
+	if n == "" || !unicode.IsLetter([]rune(n)[0]) || token.Lookup(n).IsKeyword() {
+		n = "r" + n
+	}
+	return n
+}
+
+func upperFirst(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
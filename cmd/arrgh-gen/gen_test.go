@@ -0,0 +1,157 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/kortschak/arrgh"
+)
+
+var stats = arrgh.Package{Library: arrgh.SystemLibrary, Name: "stats"}
+
+func TestGenerate(t *testing.T) {
+	sigs := []signature{
+		{
+			Signature: &arrgh.Signature{
+				Function: arrgh.Function{Package: stats, Name: "rnorm"},
+				Args: []arrgh.Arg{
+					{Name: "n"},
+					{Name: "mean", Default: "0", HasDefault: true},
+					{Name: "sd", Default: "1", HasDefault: true},
+				},
+			},
+			title: "The Normal Distribution",
+		},
+		{
+			Signature: &arrgh.Signature{
+				Function: arrgh.Function{Package: stats, Name: "t.test"},
+				Args: []arrgh.Arg{
+					{Name: "x"},
+				},
+				Dots: true,
+			},
+		},
+		{
+			Signature: &arrgh.Signature{
+				Function: arrgh.Function{Package: stats, Name: "%in%"},
+			},
+		},
+		{
+			Signature: &arrgh.Signature{
+				Function: arrgh.Function{Package: stats, Name: "sd"},
+				Args: []arrgh.Arg{
+					{Name: "x"},
+					{Name: "na.rm", Default: "FALSE", HasDefault: true},
+					{Name: "type"},
+					{Name: "s"},
+					{Name: "arrgh"},
+					{Name: "string"},
+				},
+			},
+		},
+		{
+			Signature: &arrgh.Signature{
+				Function: arrgh.Function{Package: stats, Name: "wilcox.test"},
+				Args: []arrgh.Arg{
+					{Name: "alternative", Default: `c("two.sided", "less", "greater")`, HasDefault: true},
+					{Name: "mu", Default: "0", HasDefault: true},
+					{Name: "B", Default: "2000L", HasDefault: true},
+					{Name: "tol.root", Default: "1e-04", HasDefault: true},
+					{Name: "conf.level", Default: "NULL", HasDefault: true},
+				},
+			},
+		},
+	}
+	src, err := generate("stats", stats, sigs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := string(src)
+	for _, want := range []string{
+		"// Code generated by arrgh-gen; DO NOT EDIT.",
+		"package stats",
+		`// The R documentation for rnorm is titled "The Normal Distribution".`,
+		"func Rnorm(ctx context.Context, s *arrgh.Session, n interface{}, mean, sd *float64) (arrgh.Value, error) {",
+		`"n": n,`,
+		"if mean != nil {\n\t\targs[\"mean\"] = *mean\n\t}",
+		"func TTest(ctx context.Context, s *arrgh.Session, x interface{}, dots map[string]interface{}) (arrgh.Value, error) {",
+		"func Sd(ctx context.Context, s *arrgh.Session, x interface{}, naRm *bool, type_, s2, arrgh2, string2 interface{}) (arrgh.Value, error) {",
+		`args["na.rm"] = *naRm`,
+		`"arrgh":  arrgh2,`,
+		`"string": string2,`,
+		"func WilcoxTest(ctx context.Context, s *arrgh.Session, alternative *string, mu *float64, B *int, tolRoot *float64, confLevel interface{}) (arrgh.Value, error) {",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("generated source does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "%in%") {
+		t.Errorf("unexpected binding for operator:\n%s", got)
+	}
+}
+
+func TestScalarType(t *testing.T) {
+	for _, test := range []struct {
+		def  string
+		want string
+	}{
+		{def: "TRUE", want: "bool"},
+		{def: "F", want: "bool"},
+		{def: "0", want: "float64"},
+		{def: "-1.5", want: "float64"},
+		{def: "1e-08", want: "float64"},
+		{def: ".Machine$double.eps", want: ""},
+		{def: "10L", want: "int"},
+		{def: `"two.sided"`, want: "string"},
+		{def: `'a\'b'`, want: "string"},
+		{def: `c("pearson", "kendall", "spearman")`, want: "string"},
+		{def: `c(1, 2)`, want: ""},
+		{def: "NULL", want: ""},
+		{def: "NA", want: ""},
+	} {
+		if got := scalarType(test.def); got != test.want {
+			t.Errorf("unexpected scalar type for %s: got:%q want:%q", test.def, got, test.want)
+		}
+	}
+}
+
+func TestTitle(t *testing.T) {
+	const text = "\n_\bT_\bh_\be _\bN_\bo_\br_\bm_\ba_\bl _\bD_\bi_\bs_\bt_\br_\bi_\bb_\bu_\bt_\bi_\bo_\bn\n\nDescription:\n"
+	got, err := title(bufio.NewScanner(strings.NewReader(text)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "The Normal Distribution"; got != want {
+		t.Errorf("unexpected title: got:%q want:%q", got, want)
+	}
+}
+
+func TestNames(t *testing.T) {
+	for _, test := range []struct {
+		name            string
+		exported, param string
+		goPackage       string
+	}{
+		{name: "read.csv", exported: "ReadCsv", param: "readCsv", goPackage: "readcsv"},
+		{name: "is_na", exported: "IsNa", param: "isNa", goPackage: "isna"},
+		{name: "[.data.frame", exported: "", param: "dataFrame", goPackage: "dataframe"},
+		{name: "%in%", exported: "", param: "in", goPackage: "in"},
+		{name: "<-", exported: "", param: "arg", goPackage: "r"},
+		{name: "range", exported: "Range", param: "range_", goPackage: "rrange"},
+	} {
+		if got := exportedName(test.name); got != test.exported {
+			t.Errorf("unexpected exported name for %q: got:%q want:%q", test.name, got, test.exported)
+		}
+		if got := paramName(test.name); got != test.param {
+			t.Errorf("unexpected parameter name for %q: got:%q want:%q", test.name, got, test.param)
+		}
+		if got := packageName(test.name); got != test.goPackage {
+			t.Errorf("unexpected package name for %q: got:%q want:%q", test.name, got, test.goPackage)
+		}
+	}
+}
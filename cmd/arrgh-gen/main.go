@@ -0,0 +1,153 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// The arrgh-gen command generates Go wrappers for the exported functions
+// of an R package available on an OpenCPU server.
+//
+// Each exported R function is given a Go function with one argument per
+// formal argument of the R function. The generated functions send their
+// arguments to the server encoded as JSON and return the result as an
+// arrgh.Value that can be decoded into a Go value. Arguments whose default
+// is a logical, numeric, integer or character literal are typed as a
+// pointer to bool, float64, int or string, with nil selecting the R
+// default. Other arguments are passed as interface{} values, and are
+// not passed to R when they are nil.
+//
+// For example, the wrapper for stats::rnorm(n, mean = 0, sd = 1) is
+//
+//	func Rnorm(ctx context.Context, s *arrgh.Session, n interface{}, mean, sd *float64) (arrgh.Value, error)
+//
+// so a call using the default mean and a standard deviation of 2 is
+//
+//	sd := 2.0
+//	v, err := stats.Rnorm(ctx, s, 10, nil, &sd)
+//
+// For example, to generate wrappers for the R stats package:
+//
+//	arrgh-gen -host http://localhost:3000 -pkg stats -out stats/stats.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kortschak/arrgh"
+)
+
+func main() {
+	var (
+		host  = flag.String("host", "http://localhost:3000", "specifies the OpenCPU server host.")
+		root  = flag.String("root", "", "specifies the OpenCPU API root (default \"/ocpu\").")
+		lib   = flag.String("lib", string(arrgh.SystemLibrary), "specifies the library path of the R package.")
+		pkg   = flag.String("pkg", "", "specifies the R package to wrap (required).")
+		gopkg = flag.String("gopkg", "", "specifies the Go package name (default derived from the R package name).")
+		out   = flag.String("out", "", "specifies the output file (default stdout).")
+		help  = flag.Bool("help", false, "prints this message.")
+	)
+
+	flag.Parse()
+
+	if *help {
+		flag.Usage()
+		os.Exit(0)
+	}
+	if *pkg == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *gopkg == "" {
+		*gopkg = packageName(*pkg)
+	}
+
+	s, err := arrgh.NewRemoteSession(*host, *root, 10*time.Second)
+	if err != nil {
+		log.Fatalf("error opening opencpu connection: %v", err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	p := arrgh.Package{Library: arrgh.Library(*lib), Name: *pkg}
+	fns, err := s.Functions(ctx, p)
+	if err != nil {
+		log.Fatalf("error listing functions: %v", err)
+	}
+	var sigs []signature
+	for _, f := range fns {
+		sig, err := s.Signature(ctx, f)
+		if err != nil {
+			var e *arrgh.Error
+			if errors.As(err, &e) && e.StatusCode < http.StatusInternalServerError {
+				// Not a function.
+				continue
+			}
+			log.Fatalf("error getting signature of %s::%s: %v", *pkg, f.Name, err)
+		}
+		title, err := manTitle(ctx, s, f)
+		if err != nil {
+			log.Printf("no title for %s::%s: %v", *pkg, f.Name, err)
+		}
+		sigs = append(sigs, signature{Signature: sig, title: title})
+	}
+
+	src, err := generate(*gopkg, p, sigs)
+	if err != nil {
+		log.Fatalf("error generating code: %v", err)
+	}
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	err = ioutil.WriteFile(*out, src, 0o644)
+	if err != nil {
+		log.Fatalf("error writing output: %v", err)
+	}
+}
+
+// manTitle returns the title of the manual page for f.
+func manTitle(ctx context.Context, s *arrgh.Session, f arrgh.Function) (string, error) {
+	r, err := s.Manual(ctx, arrgh.Topic{Package: f.Package, Name: f.Name}, arrgh.ManText)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	return title(bufio.NewScanner(r))
+}
+
+// title returns the first non-blank line of the text scanned by sc with
+// overstrike emphasis removed.
+func title(sc *bufio.Scanner) (string, error) {
+	for sc.Scan() {
+		t := strings.TrimSpace(stripOverstrike(sc.Text()))
+		if t != "" {
+			return t, nil
+		}
+	}
+	return "", sc.Err()
+}
+
+// stripOverstrike removes the backspace overstrike sequences that
+// R uses to mark emphasis in plain text help.
+func stripOverstrike(s string) string {
+	if !strings.Contains(s, "\b") {
+		return s
+	}
+	var b strings.Builder
+	r := []rune(s)
+	for i := 0; i < len(r); i++ {
+		if i+1 < len(r) && r[i+1] == '\b' {
+			i++
+			continue
+		}
+		b.WriteRune(r[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,289 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/kortschak/arrgh"
+)
+
+func init() {
+	register(&command{
+		name:    "eval",
+		args:    "[-format f] <expr>",
+		summary: "evaluate an R expression",
+		run:     evalCmd,
+	})
+	register(&command{
+		name:    "call",
+		args:    "[-format f] <pkg::fn> [name=value...]",
+		summary: "call an R function",
+		run:     callCmd,
+	})
+	register(&command{
+		name:    "get",
+		args:    "[-format f] [-o file] <key>[/path]",
+		summary: "fetch a session object",
+		run:     getCmd,
+	})
+	register(&command{
+		name:    "upload",
+		args:    "[-fn pkg::fn] [-arg name] [-format f] <file> [name=value...]",
+		summary: "upload a file to an R function",
+		run:     uploadCmd,
+	})
+}
+
+// formatFlag adds the -format flag to flags.
+func formatFlag(flags *flag.FlagSet) *string {
+	return flags.String("format", string(arrgh.Print), "specifies the output format of the result (print, json, csv, tab, ...).")
+}
+
+func evalCmd(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	format := formatFlag(flags)
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errUsage
+	}
+	s, err := e.session()
+	if err != nil {
+		return err
+	}
+	res, err := eval(ctx, s, strings.Join(flags.Args(), " "))
+	if err != nil {
+		return err
+	}
+	return writeResult(ctx, e, s, res, arrgh.Format(*format))
+}
+
+// eval evaluates the R expression expr, returning the session result.
+func eval(ctx context.Context, s *arrgh.Session, expr string) (*arrgh.Result, error) {
+	return s.Call(ctx, "library/base/R/identity", "application/x-www-form-urlencoded", nil,
+		strings.NewReader("x="+url.QueryEscape(expr)))
+}
+
+func callCmd(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	format := formatFlag(flags)
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errUsage
+	}
+	fn, err := parseFunction(e.lib, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	callArgs, err := parseArgs(flags.Args()[1:])
+	if err != nil {
+		return err
+	}
+	s, err := e.session()
+	if err != nil {
+		return err
+	}
+	res, err := call(ctx, s, fn, callArgs)
+	if err != nil {
+		return err
+	}
+	return writeResult(ctx, e, s, res, arrgh.Format(*format))
+}
+
+func uploadCmd(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	var (
+		fnName = flags.String("fn", "base::identity", "specifies the R function receiving the file.")
+		arg    = flags.String("arg", "x", "specifies the argument name of the file.")
+		format = formatFlag(flags)
+	)
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errUsage
+	}
+	fn, err := parseFunction(e.lib, *fnName)
+	if err != nil {
+		return err
+	}
+	callArgs, err := parseArgs(flags.Args()[1:])
+	if err != nil {
+		return err
+	}
+	callArgs = append([]argument{{name: *arg, file: flags.Arg(0)}}, callArgs...)
+	s, err := e.session()
+	if err != nil {
+		return err
+	}
+	res, err := call(ctx, s, fn, callArgs)
+	if err != nil {
+		return err
+	}
+	return writeResult(ctx, e, s, res, arrgh.Format(*format))
+}
+
+func getCmd(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	var (
+		format = formatFlag(flags)
+		out    = flags.String("o", "", "specifies the output file (default stdout).")
+	)
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+	s, err := e.session()
+	if err != nil {
+		return err
+	}
+	r, err := get(ctx, s, flags.Arg(0), arrgh.Format(*format))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if *out == "" {
+		_, err = io.Copy(e.stdout, r)
+		return err
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// get returns a reader for the session object specified by ref, which
+// is a session key optionally followed by the path of an object in the
+// session. R objects, the session value when no path is given, are
+// encoded in the given format. Other objects are returned unaltered.
+func get(ctx context.Context, s *arrgh.Session, ref string, format arrgh.Format) (io.ReadCloser, error) {
+	key, obj := ref, ""
+	if i := strings.Index(ref, "/"); i >= 0 {
+		key, obj = ref[:i], strings.Trim(ref[i+1:], "/")
+	}
+	if key == "" {
+		return nil, fmt.Errorf("invalid session reference %q", ref)
+	}
+	if obj == "" {
+		obj = "R/.val"
+	}
+	p := path.Join("tmp", key, obj)
+	if strings.HasPrefix(obj, "R/") {
+		return s.Object(ctx, p, format, nil)
+	}
+	return s.Open(ctx, p, nil)
+}
+
+// writeResult writes the key of res to e's standard error and the
+// session value in the given format to its standard output.
+func writeResult(ctx context.Context, e *env, s *arrgh.Session, res *arrgh.Result, format arrgh.Format) error {
+	fmt.Fprintf(e.stderr, "key: %s\n", res.Key)
+	r, err := get(ctx, s, res.Key, format)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(e.stdout, r)
+	return err
+}
+
+// parseFunction returns the R function named by name, which must be
+// of the form pkg::fn, in the given library.
+func parseFunction(lib, name string) (arrgh.Function, error) {
+	i := strings.Index(name, "::")
+	if i <= 0 || i+2 == len(name) || strings.Contains(name[i+2:], ":") {
+		return arrgh.Function{}, fmt.Errorf("invalid function name %q: must be pkg::fn", name)
+	}
+	return arrgh.Function{
+		Package: arrgh.Package{Library: arrgh.Library(lib), Name: name[:i]},
+		Name:    name[i+2:],
+	}, nil
+}
+
+// argument is a named argument to an R function.
+type argument struct {
+	name string
+
+	// value is the R or JSON source of the argument.
+	value string
+
+	// file is the path of a file to upload
+	// as the argument.
+	file string
+}
+
+// parseArgs parses function arguments of the form name=value. Values
+// are R expressions, JSON values or session keys and are interpreted
+// by the server. A value of the form @path uploads the file at path.
+func parseArgs(args []string) ([]argument, error) {
+	var parsed []argument
+	for _, a := range args {
+		i := strings.Index(a, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid argument %q: must be name=value", a)
+		}
+		arg := argument{name: a[:i], value: a[i+1:]}
+		if strings.HasPrefix(arg.value, "@") {
+			arg.file, arg.value = arg.value[1:], ""
+		}
+		parsed = append(parsed, arg)
+	}
+	return parsed, nil
+}
+
+// call calls fn with the given arguments, sending them URL encoded
+// unless a file is being uploaded.
+func call(ctx context.Context, s *arrgh.Session, fn arrgh.Function, args []argument) (*arrgh.Result, error) {
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	var parts []arrgh.Part
+	form := make(url.Values)
+	for _, a := range args {
+		if a.file == "" {
+			parts = append(parts, arrgh.Field(a.name, a.value))
+			form.Add(a.name, a.value)
+			continue
+		}
+		f, err := os.Open(a.file)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+		parts = append(parts, arrgh.File(a.name, f, ""))
+	}
+	p := path.Join(string(fn.Package.Library), fn.Package.Name, "R", fn.Name)
+	if len(files) == 0 {
+		return s.Call(ctx, p, "application/x-www-form-urlencoded", nil, strings.NewReader(form.Encode()))
+	}
+	content, body, err := arrgh.StreamMultipartParts(parts...)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return s.Call(ctx, p, content, nil, body)
+}
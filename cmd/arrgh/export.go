@@ -0,0 +1,56 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+)
+
+func init() {
+	register(&command{
+		name:    "export",
+		args:    "[-dir path] <key>",
+		summary: "export all artifacts of a session to a directory",
+		run:     exportCmd,
+	})
+}
+
+func exportCmd(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	dir := flags.String("dir", "", "specifies the export directory (default the session key).")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+	key := flags.Arg(0)
+	if *dir == "" {
+		*dir = key
+	}
+	s, err := e.session()
+	if err != nil {
+		return err
+	}
+	m, err := s.Export(ctx, key, *dir)
+	if err != nil {
+		return err
+	}
+	var failed int
+	for _, a := range m.Artifacts {
+		if a.Error != "" {
+			failed++
+			fmt.Fprintf(e.stderr, "%s: %s\n", a.Name, a.Error)
+			continue
+		}
+		fmt.Fprintf(e.stdout, "%s\t%d\n", a.Name, a.Size)
+	}
+	if failed != 0 {
+		fmt.Fprintf(e.stderr, "%d of %d artifacts not exported\n", failed, len(m.Artifacts))
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// The arrgh command provides command-line access to an OpenCPU server.
+//
+// Usage:
+//
+//	arrgh [flags] <command> [arguments]
+//
+// The commands are:
+//
+//	eval    evaluate an R expression
+//	call    call an R function
+//	get     fetch a session object
+//	upload  upload a file to an R function
+//	repl    start an interactive R prompt
+//	serve   run a supervised local OpenCPU server
+//	export  export all artifacts of a session to a directory
+//
+// Run "arrgh <command> -help" for the usage of a command.
+//
+// The OpenCPU server is specified by the -host and -root flags, which
+// default to the values of the ARRGH_HOST and ARRGH_ROOT environment
+// variables. The -timeout flag, defaulting to ARRGH_TIMEOUT, specifies
+// how long to wait for the server to respond to the initial connection.
+// The serve command uses -root and -timeout for the server it starts.
+//
+// Commands that create a session write the session key to standard error
+// and the value of the result to standard output in the format specified
+// by their -format flag. The key may be used to refer to the value in
+// later commands. For example:
+//
+//	$ arrgh eval 'rnorm(5)'
+//	key: x0a1b2c3d4e5
+//	[1]  0.1552  1.0846 -0.2334  0.5912 -1.3002
+//	$ arrgh call stats::sd x=x0a1b2c3d4e5
+//	key: x0f6e5d4c3b2
+//	[1] 0.9064
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/kortschak/arrgh"
+)
+
+// command is an arrgh subcommand.
+type command struct {
+	name    string
+	args    string
+	summary string
+
+	// run runs the command with the given arguments.
+	// The flag set is named for the command and has
+	// no flags defined.
+	run func(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error
+}
+
+var commands = map[string]*command{}
+
+func register(c *command) { commands[c.name] = c }
+
+// env is the environment of a command.
+type env struct {
+	host    string
+	root    string
+	lib     string
+	timeout time.Duration
+
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	sess *arrgh.Session
+}
+
+// session returns a session connected to the OpenCPU server,
+// connecting on first use.
+func (e *env) session() (*arrgh.Session, error) {
+	if e.sess != nil {
+		return e.sess, nil
+	}
+	if e.host == "" {
+		return nil, errors.New("no OpenCPU host: use -host or set ARRGH_HOST")
+	}
+	s, err := arrgh.NewRemoteSession(e.host, e.root, e.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("error opening opencpu connection: %w", err)
+	}
+	e.sess = s
+	return s, nil
+}
+
+// errUsage is returned by a command when it is invoked incorrectly.
+var errUsage = errors.New("usage")
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the arrgh command with the given arguments and returns the
+// exit status.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	e := &env{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	flags := flag.NewFlagSet("arrgh", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&e.host, "host", os.Getenv("ARRGH_HOST"), "specifies the OpenCPU server host ($ARRGH_HOST).")
+	flags.StringVar(&e.root, "root", os.Getenv("ARRGH_ROOT"), "specifies the OpenCPU API root ($ARRGH_ROOT, default \"/ocpu\").")
+	flags.StringVar(&e.lib, "lib", string(arrgh.SystemLibrary), "specifies the library path of R packages.")
+	flags.DurationVar(&e.timeout, "timeout", envDuration("ARRGH_TIMEOUT", 10*time.Second), "specifies the connection timeout ($ARRGH_TIMEOUT).")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: arrgh [flags] <command> [arguments]\n\nThe commands are:")
+		names := make([]string, 0, len(commands))
+		for n := range commands {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			fmt.Fprintf(stderr, "\t%-8s%s\n", n, commands[n].summary)
+		}
+		fmt.Fprintln(stderr, "\nThe flags are:")
+		flags.PrintDefaults()
+	}
+	err := flags.Parse(args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	c, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "arrgh: unknown command %q\n", flags.Arg(0))
+		flags.Usage()
+		return 2
+	}
+
+	cflags := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	cflags.SetOutput(stderr)
+	cflags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: arrgh %s %s\n\n%s.\n", c.name, c.args, c.summary)
+		cflags.PrintDefaults()
+	}
+	err = c.run(ctx, e, cflags, flags.Args()[1:])
+	if e.sess != nil {
+		e.sess.Close()
+	}
+	switch {
+	case err == nil:
+		return 0
+	case err == flag.ErrHelp:
+		return 0
+	case err == errUsage:
+		cflags.Usage()
+		return 2
+	case isFlagError(err):
+		return 2
+	default:
+		fmt.Fprintf(stderr, "arrgh %s: %v\n", c.name, err)
+		return 1
+	}
+}
+
+// flagError is a flag parsing error that has already been reported.
+type flagError struct{ error }
+
+func isFlagError(err error) bool {
+	var e flagError
+	return errors.As(err, &e)
+}
+
+// parseFlags parses the command's arguments.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	if err != nil && err != flag.ErrHelp {
+		return flagError{err}
+	}
+	return err
+}
+
+// envDuration returns the duration held in the named environment variable,
+// or def if it is not set or is invalid.
+func envDuration(name string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(name))
+	if err != nil {
+		return def
+	}
+	return d
+}
@@ -0,0 +1,135 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/kortschak/arrgh/ocputest"
+)
+
+var keyLine = regexp.MustCompile(`^key: (x[0-9a-f]{10,})\n$`)
+
+func TestCommands(t *testing.T) {
+	srv := ocputest.NewServer()
+	defer srv.Close()
+
+	// arrghOutput runs the arrgh command with the given arguments.
+	arrghOutput := func(args ...string) (stdout, stderr string, status int) {
+		var out, errs bytes.Buffer
+		status = run(context.Background(), append([]string{"-host", srv.URL}, args...), nil, &out, &errs)
+		return out.String(), errs.String(), status
+	}
+	// arrgh runs the arrgh command with the given arguments, returning
+	// the session key reported on stderr. Any other output to stderr
+	// from a successful command is an error.
+	arrgh := func(args ...string) (stdout, key string, status int) {
+		t.Helper()
+		stdout, stderr, status := arrghOutput(args...)
+		m := keyLine.FindStringSubmatch(stderr)
+		if m != nil {
+			key = m[1]
+		} else if stderr != "" && status == 0 {
+			t.Errorf("unexpected stderr for %q: %s", args, stderr)
+		}
+		return stdout, key, status
+	}
+
+	out, key, status := arrgh("eval", "[1,2,3]")
+	if status != 0 || key == "" {
+		t.Fatalf("unexpected eval outcome: status:%d key:%q", status, key)
+	}
+	if want := "[1] 1 2 3\n"; out != want {
+		t.Errorf("unexpected eval output: got:%q want:%q", out, want)
+	}
+
+	out, sumKey, status := arrgh("call", "-format", "json", "base::sum", "x="+key)
+	if status != 0 || sumKey == "" {
+		t.Fatalf("unexpected call outcome: status:%d key:%q", status, sumKey)
+	}
+	if want := "[6]\n"; out != want {
+		t.Errorf("unexpected call output: got:%q want:%q", out, want)
+	}
+
+	out, _, status = arrgh("get", "-format", "json", key)
+	if status != 0 || out != "[1,2,3]\n" {
+		t.Errorf("unexpected get output: status:%d out:%q", status, out)
+	}
+	out, _, status = arrgh("get", sumKey+"/stdout")
+	if status != 0 || out != "[1] 6\n" {
+		t.Errorf("unexpected get stdout output: status:%d out:%q", status, out)
+	}
+	dst := filepath.Join(t.TempDir(), "val.csv")
+	_, _, status = arrgh("get", "-format", "csv", "-o", dst, key)
+	b, err := ioutil.ReadFile(dst)
+	if status != 0 || err != nil || string(b) != "x\n1\n2\n3\n" {
+		t.Errorf("unexpected get file output: status:%d err:%v content:%q", status, err, b)
+	}
+
+	csv := filepath.Join(t.TempDir(), "data.csv")
+	err = ioutil.WriteFile(csv, []byte("a,b\n1,2\n3,4\n"), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	out, csvKey, status := arrgh("upload", "-fn", "utils::read.csv", "-arg", "file", "-format", "json", csv, "header=TRUE")
+	if status != 0 || csvKey == "" {
+		t.Fatalf("unexpected upload outcome: status:%d key:%q", status, csvKey)
+	}
+	if want := `[{"a":1,"b":2},{"a":3,"b":4}]` + "\n"; out != want {
+		t.Errorf("unexpected upload output: got:%q want:%q", out, want)
+	}
+	out, _, status = arrgh("get", csvKey+"/files/data.csv")
+	if status != 0 || out != "a,b\n1,2\n3,4\n" {
+		t.Errorf("unexpected uploaded file: status:%d out:%q", status, out)
+	}
+
+	exported := filepath.Join(t.TempDir(), "export")
+	out, stderr, status := arrghOutput("export", "-dir", exported, csvKey)
+	if status != 0 || !strings.Contains(out, "files/data.csv\t12\n") {
+		t.Errorf("unexpected export output: status:%d out:%q", status, out)
+	}
+	// The fake server cannot serialise R objects to RDS.
+	wantStderr := `R/read.csv.rds: arrgh: opencpu error: 404 Not Found: 404 page not found
+value.rds: arrgh: opencpu error: 400 Bad Request: ocputest: unsupported format: rds
+2 of 9 artifacts not exported
+`
+	if stderr != wantStderr {
+		t.Errorf("unexpected export stderr:\ngot:\n%s\nwant:\n%s", stderr, wantStderr)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(exported, "files", "data.csv"))
+	if err != nil || string(b) != "a,b\n1,2\n3,4\n" {
+		t.Errorf("unexpected exported file: %q %v", b, err)
+	}
+
+	for _, args := range [][]string{
+		{"call", "base::stop", "x=\"failed\""},
+		{"get", "x0000000000"},
+		{"serve", "-r", filepath.Join(t.TempDir(), "R")},
+	} {
+		_, _, status = arrgh(args...)
+		if status != 1 {
+			t.Errorf("unexpected status for %q: got:%d want:1", args, status)
+		}
+	}
+	for _, args := range [][]string{
+		{},
+		{"unknown"},
+		{"eval"},
+		{"call", "sum"},
+		{"call", "base::sum", "x"},
+	} {
+		var out, errs bytes.Buffer
+		status = run(context.Background(), append([]string{"-host", srv.URL}, args...), nil, &out, &errs)
+		if status == 0 || !strings.Contains(errs.String(), "usage") && !strings.Contains(errs.String(), "invalid") {
+			t.Errorf("unexpected outcome for %q: status:%d stderr:%s", args, status, &errs)
+		}
+	}
+}
@@ -0,0 +1,658 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kortschak/arrgh"
+)
+
+func init() {
+	register(&command{
+		name:    "repl",
+		args:    "[-history file]",
+		summary: "start an interactive R prompt",
+		run:     replCmd,
+	})
+}
+
+const replHelp = `Each input is evaluated as an R expression on the server and its
+printed output is shown. Inputs are continued over several lines until
+they are complete.
+
+Assignments of the form "name <- expr" or "name = expr" store the value
+of expr on the server under a session key. Later inputs may refer to the
+value by name, except where the name is an argument or local variable
+of a function defined in the input. Session keys may also be used
+directly.
+
+Inputs are recorded in the history, which is kept in the history file
+between sessions.
+
+The following commands are available:
+	:help      show this message
+	:ls        list named values and their session keys
+	:history   show the numbered input history
+	:!n        evaluate history entry n again
+	:!!        evaluate the last history entry again
+	:quit      leave the prompt (as does end of input)
+`
+
+func replCmd(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	history := flags.String("history", defaultHistory(), "specifies the history file (empty for no history file).")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errUsage
+	}
+	s, err := e.session()
+	if err != nil {
+		return err
+	}
+	r := &repl{s: s, out: e.stdout, vars: make(map[string]string)}
+	if *history != "" {
+		r.loadHistory(*history)
+		f, err := os.OpenFile(*history, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			fmt.Fprintf(e.stderr, "arrgh repl: history not saved: %v\n", err)
+		} else {
+			defer f.Close()
+			r.histFile = f
+		}
+	}
+	return r.run(ctx, e.stdin)
+}
+
+// defaultHistory returns the default history file path.
+func defaultHistory() string {
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, ".arrgh_history")
+}
+
+// repl is an interactive R prompt that emulates a persistent R session
+// by holding named values as OpenCPU session keys.
+type repl struct {
+	s   *arrgh.Session
+	out io.Writer
+
+	// vars maps R names to the session
+	// keys holding their values.
+	vars map[string]string
+
+	history  []string
+	histFile io.Writer
+}
+
+// run reads and evaluates inputs from in until it is exhausted or
+// the user quits.
+func (r *repl) run(ctx context.Context, in io.Reader) error {
+	sc := bufio.NewScanner(in)
+	var input strings.Builder
+	prompt := "> "
+	for {
+		fmt.Fprint(r.out, prompt)
+		if !sc.Scan() {
+			fmt.Fprintln(r.out)
+			return sc.Err()
+		}
+		line := sc.Text()
+		var expr string
+		if input.Len() == 0 {
+			switch cmd := strings.TrimSpace(line); {
+			case cmd == "":
+				continue
+			case strings.HasPrefix(cmd, ":!"):
+				var ok bool
+				expr, ok = r.recall(cmd[len(":!"):])
+				if !ok {
+					continue
+				}
+				fmt.Fprintln(r.out, expr)
+			case strings.HasPrefix(cmd, ":"):
+				if r.command(cmd) {
+					return nil
+				}
+				continue
+			}
+		}
+		if expr == "" {
+			if input.Len() != 0 {
+				input.WriteByte('\n')
+			}
+			input.WriteString(line)
+			if !complete(input.String()) {
+				prompt = "+ "
+				continue
+			}
+			expr = input.String()
+			input.Reset()
+			prompt = "> "
+		}
+
+		r.record(expr)
+		err := r.eval(ctx, expr)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			var e *arrgh.Error
+			if errors.As(err, &e) {
+				fmt.Fprintln(r.out, strings.TrimSpace(e.Message))
+			} else {
+				fmt.Fprintf(r.out, "Error: %v\n", err)
+			}
+		}
+	}
+}
+
+// recall returns the history entry numbered by n, as shown by the
+// :history command, or the last entry if n is "!". If there is no such
+// entry, a message is written to the REPL's output and ok is false.
+func (r *repl) recall(n string) (expr string, ok bool) {
+	if n == "!" {
+		n = strconv.Itoa(len(r.history))
+	}
+	i, err := strconv.Atoi(n)
+	if err != nil || i < 1 || i > len(r.history) {
+		fmt.Fprintf(r.out, "no history entry %s: try :history\n", n)
+		return "", false
+	}
+	return r.history[i-1], true
+}
+
+// command runs the REPL command cmd, returning whether the user quit.
+func (r *repl) command(cmd string) (quit bool) {
+	switch cmd {
+	case ":quit", ":q":
+		return true
+	case ":help", ":h":
+		fmt.Fprint(r.out, replHelp)
+	case ":ls":
+		names := make([]string, 0, len(r.vars))
+		for n := range r.vars {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			fmt.Fprintf(r.out, "%s\t%s\n", n, r.vars[n])
+		}
+	case ":history":
+		for i, h := range r.history {
+			fmt.Fprintf(r.out, "%4d  %s\n", i+1, strings.ReplaceAll(h, "\n", "\n      "))
+		}
+	default:
+		fmt.Fprintf(r.out, "unknown command %s: try :help\n", cmd)
+	}
+	return false
+}
+
+// eval evaluates expr, storing the session key of the value if expr is
+// an assignment, and otherwise writing the printed output of the
+// evaluation to the REPL's output.
+func (r *repl) eval(ctx context.Context, expr string) error {
+	name, rhs := assignment(expr)
+	if name != "" {
+		expr = rhs
+	}
+	res, err := eval(ctx, r.s, substitute(expr, r.vars))
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		r.vars[name] = res.Key
+	} else {
+		rc, err := get(ctx, r.s, res.Key+"/stdout", "")
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(r.out, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	for _, p := range res.Paths {
+		if p == "tmp/"+res.Key+"/warnings" {
+			warnings, err := r.s.Warnings(ctx, res.Key)
+			if err != nil {
+				return err
+			}
+			if len(warnings) != 0 {
+				fmt.Fprintln(r.out, "Warning message:")
+				for _, w := range warnings {
+					fmt.Fprintln(r.out, w)
+				}
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// record adds expr to the history.
+func (r *repl) record(expr string) {
+	r.history = append(r.history, expr)
+	if r.histFile != nil {
+		// Multi-line inputs are stored on one line with
+		// escaped newlines.
+		fmt.Fprintln(r.histFile, strings.ReplaceAll(strings.ReplaceAll(expr, `\`, `\\`), "\n", `\n`))
+	}
+}
+
+// loadHistory reads the history file at path.
+func (r *repl) loadHistory(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	unescape := strings.NewReplacer(`\\`, `\`, `\n`, "\n")
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		r.history = append(r.history, unescape.Replace(sc.Text()))
+	}
+}
+
+// assignmentPattern matches a top-level R assignment to a name.
+var assignmentPattern = regexp.MustCompile(`^\s*([A-Za-z.][A-Za-z0-9._]*|` + "`[^`]+`" + `)\s*(?:<-|=)([^=][\s\S]*)$`)
+
+// assignment returns the name and right hand side of expr if it is an
+// assignment to a name, and empty strings otherwise.
+func assignment(expr string) (name, rhs string) {
+	m := assignmentPattern.FindStringSubmatch(expr)
+	if m == nil || strings.TrimSpace(m[2]) == "" {
+		return "", ""
+	}
+	return strings.Trim(m[1], "`"), m[2]
+}
+
+// substitute returns expr with references to the named values in vars
+// replaced by their session keys. Names in strings and comments, names
+// of arguments and list elements, names qualified by a namespace or
+// following $ or @, and names that are formal arguments or local
+// variables of an enclosing function are not replaced.
+func substitute(expr string, vars map[string]string) string {
+	if len(vars) == 0 {
+		return expr
+	}
+	var (
+		b      strings.Builder
+		depth  int
+		scopes []*scope
+	)
+	toks := tokens(expr)
+	for i, t := range toks {
+		// Close the scopes of functions whose bodies end here.
+		for n := len(scopes); n > 0; n = len(scopes) {
+			if !scopes[n-1].ends(t, depth) {
+				break
+			}
+			scopes = scopes[:n-1]
+		}
+
+		switch t.kind {
+		case ident:
+			if isFunction(toks, i) {
+				scopes = append(scopes, &scope{depth: depth, names: formals(toks, i)})
+				break
+			}
+			name := strings.Trim(t.text, "`")
+			if n := len(scopes); n != 0 && scopes[n-1].body && local(toks, i, depth, scopes[n-1]) {
+				scopes[n-1].names[name] = true
+			}
+			if key, ok := vars[name]; ok && !shadowed(scopes, name) && !qualified(toks, i) && !argName(toks, i) {
+				b.WriteString(key)
+				continue
+			}
+		case punct:
+			if t.text == `\` && isFunction(toks, i) {
+				scopes = append(scopes, &scope{depth: depth, names: formals(toks, i)})
+				break
+			}
+			switch t.text {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				depth--
+			}
+			if n := len(scopes); n != 0 {
+				scopes[n-1].step(t, depth)
+			}
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+// scope is the scope of a function in an expression.
+type scope struct {
+	// depth is the bracket depth of the
+	// function keyword.
+	depth int
+
+	// names holds the formal arguments
+	// and local variables of the function.
+	names map[string]bool
+
+	// body indicates that the formal
+	// arguments have been read, opened
+	// that the body has started and
+	// braced that it is within braces.
+	body   bool
+	opened bool
+	braced bool
+}
+
+// step updates the state of the scope after the bracket t, which leaves
+// the expression at the given depth.
+func (s *scope) step(t token, depth int) {
+	if !s.body && t.text == ")" && depth == s.depth {
+		s.body = true
+	}
+}
+
+// ends returns whether the function body of the scope ends before the
+// token t, found at the given depth.
+func (s *scope) ends(t token, depth int) bool {
+	if depth < s.depth {
+		return true
+	}
+	if !s.body || depth != s.depth {
+		return false
+	}
+	if !s.opened {
+		if t.kind != space && t.kind != comment {
+			s.opened = true
+			s.braced = t.text == "{"
+		}
+		return false
+	}
+	if s.braced {
+		// The closing brace has been written.
+		return true
+	}
+	switch {
+	case t.kind == punct && (t.text == "," || t.text == ";" || t.text == ")" || t.text == "]" || t.text == "}"):
+		return true
+	case t.kind == space && strings.Contains(t.text, "\n"):
+		return true
+	}
+	return false
+}
+
+// shadowed returns whether name is a formal argument or local variable
+// in any of the scopes.
+func shadowed(scopes []*scope, name string) bool {
+	for _, s := range scopes {
+		if s.names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// isFunction returns whether toks[i] is the function keyword or its
+// backslash shorthand followed by formal arguments.
+func isFunction(toks []token, i int) bool {
+	if toks[i].text != "function" && toks[i].text != `\` {
+		return false
+	}
+	j := next(toks, i)
+	return j < len(toks) && toks[j].text == "("
+}
+
+// formals returns the names of the formal arguments of the function
+// whose keyword is at toks[i].
+func formals(toks []token, i int) map[string]bool {
+	names := make(map[string]bool)
+	var depth int
+	prev := ""
+	for j := next(toks, i); j < len(toks); j++ {
+		t := toks[j]
+		switch t.kind {
+		case space, comment:
+			continue
+		case ident:
+			if depth == 1 && (prev == "(" || prev == ",") {
+				names[strings.Trim(t.text, "`")] = true
+			}
+		case punct:
+			switch t.text {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				depth--
+			}
+		}
+		if depth == 0 {
+			break
+		}
+		prev = t.text
+	}
+	return names
+}
+
+// local returns whether the identifier at toks[i], at the given depth,
+// is assigned to within the body of the function with scope s.
+func local(toks []token, i, depth int, s *scope) bool {
+	j := next(toks, i)
+	if j == len(toks) {
+		return false
+	}
+	switch toks[j].text {
+	case "<-":
+		return !qualified(toks, i)
+	case "=":
+		// Equals signs are assignments only at the
+		// top level of the body.
+		top := s.depth
+		if s.braced {
+			top++
+		}
+		return depth == top && !qualified(toks, i)
+	}
+	return false
+}
+
+// next returns the index of the first token after toks[i] that is not
+// space or a comment, or len(toks) if there is none.
+func next(toks []token, i int) int {
+	for i++; i < len(toks); i++ {
+		if toks[i].kind != space && toks[i].kind != comment {
+			break
+		}
+	}
+	return i
+}
+
+// qualified returns whether the identifier at toks[i] is preceded by
+// an operator that makes it a member or namespace name.
+func qualified(toks []token, i int) bool {
+	for i--; i >= 0; i-- {
+		if toks[i].kind == space {
+			continue
+		}
+		switch toks[i].text {
+		case "$", "@", "::", ":::":
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// argName returns whether the identifier at toks[i] is followed by a
+// single equals sign, making it an argument or element name.
+func argName(toks []token, i int) bool {
+	for i++; i < len(toks); i++ {
+		if toks[i].kind == space {
+			continue
+		}
+		return toks[i].text == "="
+	}
+	return false
+}
+
+// complete returns whether expr is a complete R expression: all
+// brackets are closed, strings terminated and it does not end with
+// a binary operator.
+func complete(expr string) bool {
+	var depth int
+	var last token
+	for _, t := range tokens(expr) {
+		switch t.kind {
+		case unterminated:
+			return false
+		case space, comment:
+			continue
+		case punct:
+			switch t.text {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				depth--
+			}
+		}
+		last = t
+	}
+	if depth > 0 {
+		return false
+	}
+	if last.kind == punct {
+		switch last.text {
+		case "+", "-", "*", "/", "^", "<-", "<<-", "=", "==", "!=", "<", ">", "<=", ">=",
+			"&", "&&", "|", "||", "~", ",", "|>", "$", "@", ":", "::", ":::", "!":
+			return false
+		}
+	}
+	if last.kind == operator {
+		// %op% infix operators.
+		return false
+	}
+	return true
+}
+
+// tokenKind is the kind of an R lexical token.
+type tokenKind int
+
+const (
+	space tokenKind = iota
+	comment
+	str
+	unterminated
+	ident
+	number
+	operator
+	punct
+)
+
+// token is an R lexical token.
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// puncts holds multi-character operators, longest first.
+var puncts = []string{":::", "<<-", "::", "<-", "->", "==", "!=", "<=", ">=", "&&", "||", "|>"}
+
+// tokens splits expr into R lexical tokens. The concatenation of the
+// text of the tokens is expr.
+func tokens(expr string) []token {
+	var toks []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		j := i + 1
+		var kind tokenKind
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			kind = space
+			for j < len(expr) && strings.IndexByte(" \t\n\r", expr[j]) >= 0 {
+				j++
+			}
+		case c == '#':
+			kind = comment
+			for j < len(expr) && expr[j] != '\n' {
+				j++
+			}
+		case c == '"' || c == '\'' || c == '`':
+			kind = unterminated
+			for j < len(expr) {
+				if expr[j] == '\\' {
+					j += 2
+					continue
+				}
+				j++
+				if expr[j-1] == c {
+					kind = str
+					break
+				}
+			}
+			if j > len(expr) {
+				j = len(expr)
+			}
+			if c == '`' && kind == str {
+				kind = ident
+			}
+		case c == '%':
+			kind = unterminated
+			for j < len(expr) && expr[j] != '\n' {
+				j++
+				if expr[j-1] == '%' {
+					kind = operator
+					break
+				}
+			}
+		case isIdentStart(c):
+			kind = ident
+			for j < len(expr) && isIdent(expr[j]) {
+				j++
+			}
+			if c == '.' && j > i+1 && isDigit(expr[i+1]) {
+				kind = number
+			}
+		case isDigit(c):
+			kind = number
+			for j < len(expr) && (isIdent(expr[j]) || (expr[j] == '-' || expr[j] == '+') && (expr[j-1] == 'e' || expr[j-1] == 'E')) {
+				j++
+			}
+		default:
+			kind = punct
+			for _, p := range puncts {
+				if strings.HasPrefix(expr[i:], p) {
+					j = i + len(p)
+					break
+				}
+			}
+		}
+		toks = append(toks, token{kind: kind, text: expr[i:j]})
+		i = j
+	}
+	return toks
+}
+
+func isIdentStart(c byte) bool {
+	return c == '.' || c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c >= 0x80
+}
+
+func isIdent(c byte) bool { return isIdentStart(c) || isDigit(c) }
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
@@ -0,0 +1,149 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/kortschak/arrgh/ocputest"
+)
+
+var completeTests = []struct {
+	expr string
+	want bool
+}{
+	{expr: "1 + 2", want: true},
+	{expr: "1 +", want: false},
+	{expr: "f(1,", want: false},
+	{expr: "f(1,\n2)", want: true},
+	{expr: "{\n x <- 1", want: false},
+	{expr: "{\n x <- 1\n}", want: true},
+	{expr: `"unterminated`, want: false},
+	{expr: `"a \" b"`, want: true},
+	{expr: `"(" # (`, want: true},
+	{expr: "x %in%", want: false},
+	{expr: "x %in% y", want: true},
+	{expr: "x <-", want: false},
+	{expr: "1.5e-3", want: true},
+}
+
+func TestComplete(t *testing.T) {
+	for _, test := range completeTests {
+		got := complete(test.expr)
+		if got != test.want {
+			t.Errorf("unexpected result for %q: got:%t want:%t", test.expr, got, test.want)
+		}
+	}
+}
+
+var assignmentTests = []struct {
+	expr     string
+	wantName string
+	wantRHS  string
+}{
+	{expr: "x <- 1", wantName: "x", wantRHS: " 1"},
+	{expr: "x.y=c(1,\n2)", wantName: "x.y", wantRHS: "c(1,\n2)"},
+	{expr: "`my var` <- 2", wantName: "my var", wantRHS: " 2"},
+	{expr: "x == 1"},
+	{expr: "f(x = 1)"},
+	{expr: "x[1] <- 2"},
+}
+
+func TestAssignment(t *testing.T) {
+	for _, test := range assignmentTests {
+		name, rhs := assignment(test.expr)
+		if name != test.wantName || rhs != test.wantRHS {
+			t.Errorf("unexpected assignment for %q: got:%q %q want:%q %q",
+				test.expr, name, rhs, test.wantName, test.wantRHS)
+		}
+	}
+}
+
+var substituteTests = []struct {
+	expr string
+	want string
+}{
+	{expr: "x + 1", want: "x0000000001 + 1"},
+	{expr: "f(x = x)", want: "f(x = x0000000001)"},
+	{expr: "l$x + y", want: "l$x + x0000000002"},
+	{expr: "pkg::x", want: "pkg::x"},
+	{expr: `"x" # x`, want: `"x" # x`},
+	{expr: "x == y", want: "x0000000001 == x0000000002"},
+	{expr: "xx + x1", want: "xx + x1"},
+	{expr: "`my var`[1]", want: "x0000000003[1]"},
+	{expr: "l@x", want: "l@x"},
+	{expr: "f(y = 1)", want: "f(y = 1)"},
+	{expr: "function(x) x", want: "function(x) x"},
+	{expr: "function(x) x + y", want: "function(x) x + x0000000002"},
+	{expr: `\(x) x`, want: `\(x) x`},
+	{expr: "sapply(y, function(x) x * 2) + x", want: "sapply(x0000000002, function(x) x * 2) + x0000000001"},
+	{expr: "function(x = y, n = length(x)) x[n]", want: "function(x = x0000000002, n = length(x)) x[n]"},
+	{expr: "f(function(a) {\n y <- a\n x + y\n}, y)", want: "f(function(a) {\n y <- a\n x0000000001 + y\n}, x0000000002)"},
+	{expr: "function(a)\n{\n a\n}\nx", want: "function(a)\n{\n a\n}\nx0000000001"},
+	{expr: "(function(x) x)(x)", want: "(function(x) x)(x0000000001)"},
+}
+
+func TestSubstitute(t *testing.T) {
+	vars := map[string]string{"x": "x0000000001", "y": "x0000000002", "my var": "x0000000003"}
+	for _, test := range substituteTests {
+		got := substitute(test.expr, vars)
+		if got != test.want {
+			t.Errorf("unexpected substitution for %q: got:%q want:%q", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestREPL(t *testing.T) {
+	srv := ocputest.NewServer()
+	defer srv.Close()
+
+	history := filepath.Join(t.TempDir(), "history")
+	input := `x <- [1,2,
+3]
+x
+:ls
+y = x
+sum(y)
+total <- sum(x, y)
+total
+stop("boom")
+:!4
+:!10
+:history
+`
+	var out, errs bytes.Buffer
+	status := run(context.Background(), []string{"-host", srv.URL, "repl", "-history", history}, strings.NewReader(input), &out, &errs)
+	if status != 0 {
+		t.Fatalf("unexpected status: got:%d want:0\n%s", status, &errs)
+	}
+	got := regexp.MustCompile(`x[0-9a-f]{10,}`).ReplaceAllString(out.String(), "xKEY")
+	want := `> + > [1] 1 2 3
+> x	xKEY
+> > [1] 6
+> > [1] 12
+> invalid argument x: boom
+> sum(y)
+[1] 6
+> no history entry 10: try :history
+>    1  x <- [1,2,
+      3]
+   2  x
+   3  y = x
+   4  sum(y)
+   5  total <- sum(x, y)
+   6  total
+   7  stop("boom")
+   8  sum(y)
+> 
+`
+	if got != want {
+		t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kortschak/arrgh"
+)
+
+func init() {
+	register(&command{
+		name:    "serve",
+		args:    "[-r path] [-port n]",
+		summary: "run a supervised local OpenCPU server",
+		run:     serveCmd,
+	})
+}
+
+func serveCmd(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	var (
+		path  = flags.String("r", "", "specifies the R executable (default R in $PATH).")
+		port  = flags.Int("port", 5656, "specifies the server port.")
+		delay = flags.Duration("restart", time.Second, "specifies the delay before restarting R after it exits.")
+	)
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errUsage
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	sv := arrgh.Supervisor{
+		Path:         *path,
+		Root:         e.root,
+		Port:         *port,
+		Timeout:      e.timeout,
+		Log:          e.stderr,
+		RestartDelay: *delay,
+		Started: func(s *arrgh.Session) {
+			fmt.Fprintf(e.stdout, "serving OpenCPU at http://localhost:%d%s\n", *port, s.Root())
+		},
+		Exited: func(err error) {
+			fmt.Fprintf(e.stderr, "arrgh serve: R exited: %v: restarting\n", err)
+		},
+	}
+	return sv.Run(ctx)
+}
@@ -0,0 +1,33 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+)
+
+// Dataset returns a reader for the dataset encoded in the specified format.
+// The URL parameters are interpreted by the format's encoder. The list of a
+// package's datasets is available from Datasets. The returned reader must be
+// closed after use.
+func (s *Session) Dataset(ctx context.Context, d Dataset, format Format, params url.Values) (io.ReadCloser, error) {
+	return s.Object(ctx, d.Package.path("data", d.Name), format, params)
+}
+
+// DecodeDataset decodes the JSON encoding of the dataset into dst using
+// encoding/json. Data frames are encoded by row, so a data frame may be
+// decoded into a slice of structs or maps. The URL parameters are
+// interpreted by jsonlite.
+func (s *Session) DecodeDataset(ctx context.Context, d Dataset, dst interface{}, params url.Values) error {
+	r, err := s.Dataset(ctx, d, JSON, params)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return json.NewDecoder(r).Decode(dst)
+}
@@ -0,0 +1,55 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDataset(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ocpu/library/datasets/data/cars/json":
+			w.Write([]byte(`[{"speed":4,"dist":2},{"speed":4,"dist":10}]`))
+		case "/ocpu/library/datasets/data/cars/csv":
+			w.Write([]byte("\"speed\",\"dist\"\n4,2\n4,10\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	s := testSession(t, srv.URL)
+	ctx := context.Background()
+
+	cars := Dataset{Package: Package{Library: SystemLibrary, Name: "datasets"}, Name: "cars"}
+	var got []struct {
+		Speed float64 `json:"speed"`
+		Dist  float64 `json:"dist"`
+	}
+	err := s.DecodeDataset(ctx, cars, &got, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[1].Speed != 4 || got[1].Dist != 10 {
+		t.Errorf("unexpected dataset: got:%+v", got)
+	}
+
+	r, err := s.Dataset(ctx, cars, CSV, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("unexpected error reading dataset: %v", err)
+	}
+	if want := "\"speed\",\"dist\"\n4,2\n4,10\n"; string(b) != want {
+		t.Errorf("unexpected CSV: got:%q want:%q", b, want)
+	}
+}
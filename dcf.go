@@ -0,0 +1,270 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RecordField is a single field of a DCF record.
+type RecordField struct {
+	Name  string
+	Value string
+}
+
+// Record is a Debian Control File record. Field order is retained.
+type Record []RecordField
+
+// Get returns the value of the named field and whether it was present.
+// Field names are matched case-insensitively.
+func (r Record) Get(name string) (value string, ok bool) {
+	for _, f := range r {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// ReadDCF reads the Debian Control File formatted records from r.
+//
+// Records are separated by blank lines. Each field starts with a name
+// followed by a colon at the start of a line, and continues on following
+// lines that begin with white space. Continuation lines are trimmed and
+// joined with newlines, with a line holding only "." representing an
+// empty line.
+//
+// See https://www.debian.org/doc/debian-policy/ch-controlfields.html and
+// https://cran.r-project.org/doc/manuals/r-release/R-exts.html#The-DESCRIPTION-file.
+func ReadDCF(r io.Reader) ([]Record, error) {
+	var (
+		recs []Record
+		rec  Record
+		line int
+	)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line++
+		text := strings.TrimRight(sc.Text(), " \t\r")
+		switch {
+		case text == "":
+			if rec != nil {
+				recs = append(recs, rec)
+				rec = nil
+			}
+		case text[0] == ' ' || text[0] == '\t':
+			if rec == nil {
+				return nil, fmt.Errorf("arrgh: dcf line %d: continuation line without field", line)
+			}
+			cont := strings.TrimSpace(text)
+			if cont == "." {
+				cont = ""
+			}
+			f := &rec[len(rec)-1]
+			if f.Value == "" {
+				f.Value = cont
+			} else {
+				f.Value += "\n" + cont
+			}
+		case text[0] == '#':
+			// Comment.
+		default:
+			i := strings.Index(text, ":")
+			if i <= 0 {
+				return nil, fmt.Errorf("arrgh: dcf line %d: malformed field: %q", line, text)
+			}
+			rec = append(rec, RecordField{Name: text[:i], Value: strings.TrimSpace(text[i+1:])})
+		}
+	}
+	err := sc.Err()
+	if err != nil {
+		return nil, err
+	}
+	if rec != nil {
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+// PackageDescription is the content of an R package DESCRIPTION file.
+type PackageDescription struct {
+	Package     string
+	Type        string
+	Title       string
+	Version     string
+	Description string
+	License     string
+	Maintainer  string
+
+	// Author is the Author field of the description
+	// and Authors is the list of author names it holds.
+	Author  string
+	Authors []string
+
+	Depends   []Dependency
+	Imports   []Dependency
+	LinkingTo []Dependency
+	Suggests  []Dependency
+	Enhances  []Dependency
+
+	// Fields holds all the fields of the description.
+	Fields Record
+}
+
+// Dependency is an R package dependency.
+type Dependency struct {
+	// Name is the name of the package.
+	Name string
+
+	// Op and Version specify a version constraint
+	// on the dependency, for example ">=" and "3.5.0".
+	// They are empty if there is no constraint.
+	Op      string
+	Version string
+}
+
+func (d Dependency) String() string {
+	if d.Op == "" {
+		return d.Name
+	}
+	return fmt.Sprintf("%s (%s %s)", d.Name, d.Op, d.Version)
+}
+
+// ParseDescription parses an R package DESCRIPTION file from r. Only the
+// first record is used.
+func ParseDescription(r io.Reader) (*PackageDescription, error) {
+	recs, err := ReadDCF(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return nil, errors.New("arrgh: empty description")
+	}
+	rec := recs[0]
+	d := PackageDescription{Fields: rec}
+	for _, f := range []struct {
+		name string
+		dst  *string
+	}{
+		{"Package", &d.Package},
+		{"Type", &d.Type},
+		{"Title", &d.Title},
+		{"Version", &d.Version},
+		{"Description", &d.Description},
+		{"License", &d.License},
+		{"Maintainer", &d.Maintainer},
+		{"Author", &d.Author},
+	} {
+		*f.dst, _ = rec.Get(f.name)
+	}
+	d.Authors = splitAuthors(d.Author)
+	for _, f := range []struct {
+		name string
+		dst  *[]Dependency
+	}{
+		{"Depends", &d.Depends},
+		{"Imports", &d.Imports},
+		{"LinkingTo", &d.LinkingTo},
+		{"Suggests", &d.Suggests},
+		{"Enhances", &d.Enhances},
+	} {
+		v, ok := rec.Get(f.name)
+		if !ok {
+			continue
+		}
+		*f.dst, err = parseDependencies(v)
+		if err != nil {
+			return nil, fmt.Errorf("arrgh: invalid %s field: %w", f.name, err)
+		}
+	}
+	return &d, nil
+}
+
+// parseDependencies parses a comma-separated list of package
+// dependencies with optional version constraints.
+func parseDependencies(s string) ([]Dependency, error) {
+	var deps []Dependency
+	for _, d := range strings.Split(s, ",") {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		i := strings.Index(d, "(")
+		if i < 0 {
+			deps = append(deps, Dependency{Name: d})
+			continue
+		}
+		name := strings.TrimSpace(d[:i])
+		c := strings.TrimSpace(d[i+1:])
+		if !strings.HasSuffix(c, ")") {
+			return nil, fmt.Errorf("unterminated version constraint: %q", d)
+		}
+		c = strings.TrimSpace(strings.TrimSuffix(c, ")"))
+		op := strings.TrimRight(c, "0123456789.- \t\n")
+		if op == "" || len(op) > 2 || strings.Trim(op, "<>=!") != "" {
+			return nil, fmt.Errorf("invalid version constraint: %q", d)
+		}
+		deps = append(deps, Dependency{
+			Name:    name,
+			Op:      op,
+			Version: strings.TrimSpace(c[len(op):]),
+		})
+	}
+	return deps, nil
+}
+
+// splitAuthors returns the author names listed in an Author field,
+// removing bracketed role annotations.
+func splitAuthors(s string) []string {
+	s = strings.Join(strings.Fields(s), " ")
+	var (
+		authors []string
+		name    strings.Builder
+		depth   int
+	)
+	flush := func() {
+		n := strings.Join(strings.Fields(name.String()), " ")
+		if n != "" {
+			authors = append(authors, n)
+		}
+		name.Reset()
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '[':
+			depth++
+		case c == ']':
+			if depth > 0 {
+				depth--
+			}
+		case depth > 0:
+		case c == ',':
+			flush()
+		case strings.HasPrefix(s[i:], " and "):
+			flush()
+			i += len(" and ") - 1
+		default:
+			name.WriteByte(c)
+		}
+	}
+	flush()
+	return authors
+}
+
+// Description returns the parsed DESCRIPTION file of the package.
+func (s *Session) Description(ctx context.Context, p Package) (*PackageDescription, error) {
+	r, err := s.open(ctx, p.path("DESCRIPTION"), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ParseDescription(r)
+}
@@ -0,0 +1,124 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadDCF(t *testing.T) {
+	const dcf = `Package: x0113a3ca85
+Type: Session
+Description: This file is automatically generated
+  by OpenCPU.
+  .
+  Second paragraph.
+
+# Comment.
+Package: other
+Version: 1.0
+`
+	got, err := ReadDCF(strings.NewReader(dcf))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Record{
+		{
+			{Name: "Package", Value: "x0113a3ca85"},
+			{Name: "Type", Value: "Session"},
+			{Name: "Description", Value: "This file is automatically generated\nby OpenCPU.\n\nSecond paragraph."},
+		},
+		{
+			{Name: "Package", Value: "other"},
+			{Name: "Version", Value: "1.0"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected records:\ngot: %q\nwant:%q", got, want)
+	}
+
+	for _, bad := range []string{" leading continuation\n", "no colon\n"} {
+		_, err = ReadDCF(strings.NewReader(bad))
+		if err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+const description = `Package: opencpu
+Type: Package
+Title: Producing and Reproducing Results
+Version: 2.0.3.1
+Author: Jeroen Ooms [aut, cre], Some One [ctb] and
+    Another Person
+Maintainer: Jeroen Ooms <jeroen@berkeley.edu>
+Description: A system for embedded scientific computing
+    and reproducible research with R.
+License: Apache License 2.0
+Depends: R (>= 3.0.0)
+Imports: evaluate (>= 0.10.1), jsonlite (>= 1.4), utils,
+    methods
+Suggests: arrow
+`
+
+func TestParseDescription(t *testing.T) {
+	got, err := ParseDescription(strings.NewReader(description))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got.Fields = nil
+	want := &PackageDescription{
+		Package:     "opencpu",
+		Type:        "Package",
+		Title:       "Producing and Reproducing Results",
+		Version:     "2.0.3.1",
+		Description: "A system for embedded scientific computing\nand reproducible research with R.",
+		License:     "Apache License 2.0",
+		Maintainer:  "Jeroen Ooms <jeroen@berkeley.edu>",
+		Author:      "Jeroen Ooms [aut, cre], Some One [ctb] and\nAnother Person",
+		Authors:     []string{"Jeroen Ooms", "Some One", "Another Person"},
+		Depends:     []Dependency{{Name: "R", Op: ">=", Version: "3.0.0"}},
+		Imports: []Dependency{
+			{Name: "evaluate", Op: ">=", Version: "0.10.1"},
+			{Name: "jsonlite", Op: ">=", Version: "1.4"},
+			{Name: "utils"},
+			{Name: "methods"},
+		},
+		Suggests: []Dependency{{Name: "arrow"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected description:\ngot: %+v\nwant:%+v", got, want)
+	}
+
+	_, err = ParseDescription(strings.NewReader("Package: bad\nImports: utils (>= 1.0\n"))
+	if err == nil {
+		t.Error("expected error for malformed dependency")
+	}
+}
+
+func TestDescription(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ocpu/library/opencpu/DESCRIPTION" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(description))
+	}))
+	defer srv.Close()
+	s := testSession(t, srv.URL)
+
+	d, err := s.Description(context.Background(), Package{Library: SystemLibrary, Name: "opencpu"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Package != "opencpu" || d.Version != "2.0.3.1" {
+		t.Errorf("unexpected description: got:%+v", d)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Manifest describes the artifacts of a session exported by Export.
+type Manifest struct {
+	// Key is the session key.
+	Key string `json:"key"`
+
+	// Server is the URL of the OpenCPU API root
+	// and Version is the server's OpenCPU version.
+	// For a pool or multi-host session, Server is
+	// the server that issued the key, and is empty
+	// if the key was not issued through the session.
+	Server  string `json:"server"`
+	Version string `json:"version,omitempty"`
+
+	// Exported is the time of the export.
+	Exported time.Time `json:"exported"`
+
+	// Artifacts holds the exported artifacts.
+	Artifacts []ManifestEntry `json:"artifacts"`
+}
+
+// ManifestEntry describes an exported session artifact.
+type ManifestEntry struct {
+	// Name is the slash-separated path of the
+	// artifact relative to the export directory.
+	Name string `json:"name"`
+
+	// Path is the OpenCPU path of the artifact
+	// relative to the OpenCPU root.
+	Path string `json:"path"`
+
+	// Kind is the kind of artifact: "source",
+	// "console", "stdout", "info", "warnings",
+	// "messages", "value", "object", "graphic"
+	// or "file".
+	Kind string `json:"kind"`
+
+	// Format is the format the artifact was
+	// retrieved in, if any.
+	Format string `json:"format,omitempty"`
+
+	// Size and SHA256 are the length and the
+	// hex encoded SHA-256 digest of the artifact.
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256,omitempty"`
+
+	// Error is the error returned by the server
+	// when the artifact could not be retrieved.
+	// No file is written for the artifact.
+	Error string `json:"error,omitempty"`
+}
+
+// ManifestName is the name of the manifest file written by Export.
+const ManifestName = "manifest.json"
+
+// sessionText maps the text artifacts of a session to their
+// exported names.
+var sessionText = map[string]string{
+	"source":   "source.R",
+	"console":  "console.txt",
+	"stdout":   "stdout.txt",
+	"info":     "info.txt",
+	"warnings": "warnings.txt",
+	"messages": "messages.txt",
+}
+
+// Export writes the artifacts held by the server for the session with the
+// given key to the local directory dir, creating it if necessary, and writes a
+// manifest describing them to the file manifest.json in dir.
+//
+// The session's source, console, stdout, info, warnings and messages are written
+// as text files. The value of the session is written as value.json and value.rds
+// and other R objects are written in rds format under R/. Each graphic is written
+// under graphics/ in png and svg formats, and the files in the session's working
+// directory are written under files/.
+//
+// Artifacts that the server fails to provide are recorded in the manifest with
+// the error reported by the server. An error is returned if the session cannot
+// be listed or the export cannot be written.
+func (s *Session) Export(ctx context.Context, key, dir string) (*Manifest, error) {
+	paths, err := s.list(ctx, sessionPath(key))
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+	m := Manifest{Key: key, Server: s.server(key), Exported: time.Now().UTC()}
+	m.Version, _ = s.Version(ctx)
+
+	var entries []ManifestEntry
+	add := func(e ManifestEntry) {
+		entries = append(entries, e)
+	}
+	prefix := sessionPath(key) + "/"
+	for _, p := range paths {
+		p = s.rel(p)
+		obj := strings.TrimPrefix(p, prefix)
+		if obj == p {
+			continue
+		}
+		switch {
+		case sessionText[obj] != "":
+			add(ManifestEntry{Name: sessionText[obj], Path: p, Kind: obj})
+		case obj == "R/.val":
+			add(ManifestEntry{Name: "value.json", Path: p + "/json", Kind: "value", Format: string(JSON)})
+			add(ManifestEntry{Name: "value.rds", Path: p + "/rds", Kind: "value", Format: string(RDS)})
+		case strings.HasPrefix(obj, "R/"):
+			add(ManifestEntry{Name: obj + ".rds", Path: p + "/rds", Kind: "object", Format: string(RDS)})
+		case strings.HasPrefix(obj, "graphics/"):
+			name := strings.TrimPrefix(obj, "graphics/")
+			if name == "last" || strings.Contains(name, "/") {
+				continue
+			}
+			for _, f := range []GraphicsFormat{PNG, SVG} {
+				add(ManifestEntry{Name: obj + "." + string(f), Path: p + "/" + string(f), Kind: "graphic", Format: string(f)})
+			}
+		case strings.HasPrefix(obj, "files/") && !strings.HasSuffix(obj, "/"):
+			add(ManifestEntry{Name: obj, Path: p, Kind: "file"})
+		}
+	}
+
+	for i := range entries {
+		err = s.exportEntry(ctx, dir, &entries[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	m.Artifacts = entries
+
+	b, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, ManifestName), append(b, '\n'), 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// exportEntry retrieves the artifact described by e and writes it to dir,
+// recording its size and digest in e. Errors from the server are recorded
+// in e, while local errors are returned.
+func (s *Session) exportEntry(ctx context.Context, dir string, e *ManifestEntry) error {
+	name, err := localPath(dir, e.Name)
+	if err != nil {
+		e.Error = err.Error()
+		return nil
+	}
+	r, err := s.open(ctx, e.Path, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var oerr *Error
+		var uerr *url.Error
+		if !errors.As(err, &oerr) && !errors.As(err, &uerr) {
+			return err
+		}
+		e.Error = err.Error()
+		return nil
+	}
+	defer r.Close()
+
+	err = os.MkdirAll(filepath.Dir(name), 0o755)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	e.Size, err = io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	e.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return f.Close()
+}
@@ -0,0 +1,137 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kortschak/arrgh/ocputest"
+)
+
+func TestExport(t *testing.T) {
+	srv := ocputest.NewServer()
+	defer srv.Close()
+	s := testSession(t, srv.URL)
+	ctx := context.Background()
+
+	content, body, err := MultipartParts(StringFile("x", "data.txt", "some data", "text/plain"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res, err := s.Call(ctx, "library/base/R/warning", content, nil, body)
+	if err != nil {
+		t.Fatalf("unexpected error calling warning: %v", err)
+	}
+
+	dir := t.TempDir()
+	m, err := s.Export(ctx, res.Key, dir)
+	if err != nil {
+		t.Fatalf("unexpected error exporting session: %v", err)
+	}
+	if m.Key != res.Key || m.Version != ocputest.Version {
+		t.Errorf("unexpected manifest header: %+v", m)
+	}
+
+	type entry struct {
+		name, kind string
+		failed     bool
+	}
+	var got []entry
+	for _, a := range m.Artifacts {
+		got = append(got, entry{name: a.Name, kind: a.Kind, failed: a.Error != ""})
+		if a.Error != "" {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(a.Name)))
+		if err != nil {
+			t.Errorf("failed to read exported %s: %v", a.Name, err)
+			continue
+		}
+		if int64(len(b)) != a.Size {
+			t.Errorf("unexpected size for %s: got:%d want:%d", a.Name, len(b), a.Size)
+		}
+	}
+	want := []entry{
+		{name: "R/warning.rds", kind: "object", failed: true},
+		{name: "value.json", kind: "value"},
+		// The fake server does not provide rds encoding.
+		{name: "value.rds", kind: "value", failed: true},
+		{name: "stdout.txt", kind: "stdout"},
+		{name: "source.R", kind: "source"},
+		{name: "console.txt", kind: "console"},
+		{name: "info.txt", kind: "info"},
+		{name: "warnings.txt", kind: "warnings"},
+		{name: "files/DESCRIPTION", kind: "file"},
+		{name: "files/data.txt", kind: "file"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected artifacts:\ngot: %+v\nwant:%+v", got, want)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	var stored Manifest
+	err = json.Unmarshal(b, &stored)
+	if err != nil {
+		t.Fatalf("failed to decode manifest: %v", err)
+	}
+	if !reflect.DeepEqual(stored.Artifacts, m.Artifacts) {
+		t.Errorf("stored manifest does not match returned manifest")
+	}
+	b, err = ioutil.ReadFile(filepath.Join(dir, "warnings.txt"))
+	if err != nil || !strings.Contains(string(b), "data.txt") {
+		t.Errorf("unexpected warnings: %q %v", b, err)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(dir, "files", "data.txt"))
+	if err != nil || string(b) != "some data" {
+		t.Errorf("unexpected exported file: %q %v", b, err)
+	}
+}
+
+func TestExportRouted(t *testing.T) {
+	var hosts []Host
+	for i := 0; i < 2; i++ {
+		srv := ocputest.NewServer()
+		defer srv.Close()
+		hosts = append(hosts, Host{URL: srv.URL})
+	}
+	s, err := NewMultiHostSession(hosts, "", RoundRobin, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error opening session: %v", err)
+	}
+	ctx := context.Background()
+
+	// The manifest records the server that issued the key
+	// rather than the session's routing placeholder.
+	for range hosts {
+		res, err := s.Call(ctx, "library/base/R/identity", "application/json", nil, strings.NewReader(`{"x":1}`))
+		if err != nil {
+			t.Fatalf("unexpected error calling identity: %v", err)
+		}
+		m, err := s.Export(ctx, res.Key, t.TempDir())
+		if err != nil {
+			t.Fatalf("unexpected error exporting session: %v", err)
+		}
+		want := s.client.Transport.(*router).issuer(res.Key).base.String()
+		if m.Server != want || strings.Contains(m.Server, "arrgh.invalid") {
+			t.Errorf("unexpected manifest server: got:%q want:%q", m.Server, want)
+		}
+	}
+
+	// Keys that were not issued through the session
+	// have no known server.
+	if got := s.server("x0000000000"); got != "" {
+		t.Errorf("unexpected server for unknown key: got:%q want:\"\"", got)
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	pth "path"
+	"path/filepath"
+	"strings"
+)
+
+// Files returns the names of the files in the working directory of the
+// session with the given key.
+func (s *Session) Files(ctx context.Context, key string) ([]string, error) {
+	return s.list(ctx, sessionPath(key, "files"))
+}
+
+// OpenFile returns a reader for the named file in the working directory of
+// the session with the given key. The name is a slash-separated path relative
+// to the working directory. The returned reader must be closed after use.
+func (s *Session) OpenFile(ctx context.Context, key, name string) (io.ReadCloser, error) {
+	return s.open(ctx, sessionPath(key, "files", name), nil)
+}
+
+// ArchiveFormat is an OpenCPU session archive format.
+type ArchiveFormat string
+
+const (
+	Zip   ArchiveFormat = "zip" // Zip archive.
+	TarGz ArchiveFormat = "tar" // Gzip compressed tar archive.
+)
+
+// Archive returns a reader for an archive of the complete session with the
+// given key in the specified format. The returned reader must be closed after
+// use.
+func (s *Session) Archive(ctx context.Context, key string, format ArchiveFormat) (io.ReadCloser, error) {
+	switch format {
+	case Zip, TarGz:
+	default:
+		return nil, fmt.Errorf("arrgh: unknown archive format: %q", format)
+	}
+	return s.open(ctx, sessionPath(key, string(format)), nil)
+}
+
+// Extract retrieves an archive of the complete session with the given key in
+// the specified format and unpacks it into the local directory dir, creating dir
+// if necessary. Only directories and regular files are extracted. Archive entries
+// with absolute paths or paths that would be written outside dir result in an
+// error.
+func (s *Session) Extract(ctx context.Context, key, dir string, format ArchiveFormat) error {
+	r, err := s.Archive(ctx, key, format)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if format == Zip {
+		return unzip(r, dir)
+	}
+	return untar(r, dir)
+}
+
+// untar unpacks the gzip compressed tar stream r into dir.
+func untar(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name, err := localPath(dir, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(name, 0o755)
+		case tar.TypeReg:
+			err = writeFile(name, tr, hdr.FileInfo().Mode().Perm())
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// unzip unpacks the zip stream r into dir. Since the zip format requires
+// random access, the stream is first copied to a temporary file.
+func unzip(r io.Reader, dir string) error {
+	f, err := ioutil.TempFile("", "arrgh-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	n, err := io.Copy(f, r)
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(f, n)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+	for _, zf := range zr.File {
+		name, err := localPath(dir, zf.Name)
+		if err != nil {
+			return err
+		}
+		mode := zf.Mode()
+		switch {
+		case mode.IsDir():
+			err = os.MkdirAll(name, 0o755)
+		case mode.IsRegular():
+			var rc io.ReadCloser
+			rc, err = zf.Open()
+			if err != nil {
+				return err
+			}
+			err = writeFile(name, rc, mode.Perm())
+			rc.Close()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// localPath returns the local path for the slash-separated archive entry
+// name within dir, returning an error if the entry would be written
+// outside dir.
+func localPath(dir, name string) (string, error) {
+	invalid := name == "" ||
+		pth.IsAbs(name) ||
+		strings.Contains(name, `\`) ||
+		filepath.VolumeName(filepath.FromSlash(name)) != ""
+	for _, e := range strings.Split(name, "/") {
+		if e == ".." {
+			invalid = true
+			break
+		}
+	}
+	if invalid {
+		return "", fmt.Errorf("arrgh: invalid archive path: %q", name)
+	}
+	return filepath.Join(dir, filepath.FromSlash(pth.Clean(name))), nil
+}
+
+// writeFile writes the contents of r to a new file at path, creating
+// parent directories as necessary.
+func writeFile(path string, r io.Reader, perm os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm|0o200)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,195 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type tarEntry struct {
+	name    string
+	typ     byte
+	content string
+}
+
+func tarGz(t *testing.T, entries []tarEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		err := tw.WriteHeader(&tar.Header{Name: e.name, Typeflag: e.typ, Mode: 0o644, Size: int64(len(e.content)), Linkname: "/etc/passwd"})
+		if err != nil {
+			t.Fatalf("failed to write tar header: %v", err)
+		}
+		_, err = tw.Write([]byte(e.content))
+		if err != nil {
+			t.Fatalf("failed to write tar content: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar writer: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to close gzip writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func zipArchive(t *testing.T, entries []tarEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		switch e.typ {
+		case tar.TypeDir:
+			hdr.SetMode(os.ModeDir | 0o755)
+		case tar.TypeSymlink:
+			hdr.SetMode(os.ModeSymlink | 0o777)
+			e.content = "/etc/passwd"
+		default:
+			hdr.SetMode(0o644)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatalf("failed to write zip header: %v", err)
+		}
+		_, err = w.Write([]byte(e.content))
+		if err != nil {
+			t.Fatalf("failed to write zip content: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close zip writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestFiles(t *testing.T) {
+	entries := []tarEntry{
+		{name: "files/", typ: tar.TypeDir},
+		{name: "files/mydata.csv", typ: tar.TypeReg, content: "1,2,3\n"},
+		{name: "console", typ: tar.TypeReg, content: "> 1\n[1] 1\n"},
+		{name: "link", typ: tar.TypeSymlink},
+	}
+	archives := map[string][]byte{
+		"tar": tarGz(t, entries),
+		"zip": zipArchive(t, entries),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ocpu/tmp/x0123456789/files/":
+			w.Write([]byte("DESCRIPTION\nmydata.csv\n"))
+		case "/ocpu/tmp/x0123456789/files/mydata.csv":
+			w.Write([]byte("1,2,3\n"))
+		case "/ocpu/tmp/x0123456789/tar", "/ocpu/tmp/x0123456789/zip":
+			w.Write(archives[path.Base(r.URL.Path)])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	s := testSession(t, srv.URL)
+	ctx := context.Background()
+
+	files, err := s.Files(ctx, "x0123456789")
+	if err != nil {
+		t.Fatalf("unexpected error listing files: %v", err)
+	}
+	if want := []string{"DESCRIPTION", "mydata.csv"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("unexpected files: got:%q want:%q", files, want)
+	}
+
+	f, err := s.OpenFile(ctx, "x0123456789", "mydata.csv")
+	if err != nil {
+		t.Fatalf("unexpected error opening file: %v", err)
+	}
+	b, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("unexpected error reading file: %v", err)
+	}
+	if string(b) != "1,2,3\n" {
+		t.Errorf("unexpected file content: got:%q", b)
+	}
+
+	_, err = s.OpenFile(ctx, "x0123456789", "missing")
+	if _, ok := err.(*Error); !ok {
+		t.Errorf("expected *Error for missing file: got:%v", err)
+	}
+
+	for _, format := range []ArchiveFormat{TarGz, Zip} {
+		dir := t.TempDir()
+		err = s.Extract(ctx, "x0123456789", dir, format)
+		if err != nil {
+			t.Fatalf("unexpected error extracting %s archive: %v", format, err)
+		}
+		for name, want := range map[string]string{
+			"files/mydata.csv": "1,2,3\n",
+			"console":          "> 1\n[1] 1\n",
+		} {
+			got, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+			if err != nil {
+				t.Errorf("failed to read file extracted from %s archive: %v", format, err)
+				continue
+			}
+			if string(got) != want {
+				t.Errorf("unexpected content for %s from %s archive: got:%q want:%q", name, format, got, want)
+			}
+		}
+		if _, err := os.Lstat(filepath.Join(dir, "link")); !os.IsNotExist(err) {
+			t.Errorf("unexpected extraction of symlink from %s archive: %v", format, err)
+		}
+	}
+}
+
+func TestUntarTraversal(t *testing.T) {
+	for _, name := range []string{
+		"../escape",
+		"files/../../escape",
+		"/etc/escape",
+	} {
+		dir := t.TempDir()
+		archive := tarGz(t, []tarEntry{{name: name, typ: tar.TypeReg, content: "gotcha"}})
+		err := untar(bytes.NewReader(archive), filepath.Join(dir, "out"))
+		if err == nil {
+			t.Errorf("expected error for archive path %q", name)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "escape")); !os.IsNotExist(err) {
+			t.Errorf("unexpected file written outside destination for %q", name)
+		}
+	}
+}
+
+func TestUnzipTraversal(t *testing.T) {
+	for _, name := range []string{
+		"../escape",
+		"files/../../escape",
+		"/etc/escape",
+	} {
+		dir := t.TempDir()
+		archive := zipArchive(t, []tarEntry{{name: name, typ: tar.TypeReg, content: "gotcha"}})
+		err := unzip(bytes.NewReader(archive), filepath.Join(dir, "out"))
+		if err == nil {
+			t.Errorf("expected error for archive path %q", name)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "escape")); !os.IsNotExist(err) {
+			t.Errorf("unexpected file written outside destination for %q", name)
+		}
+	}
+}
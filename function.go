@@ -0,0 +1,138 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+)
+
+// Signature describes an R function.
+type Signature struct {
+	// Function is the described function.
+	Function Function
+
+	// Args holds the formal arguments of the
+	// function, in order, excluding "...".
+	Args []Arg
+
+	// Dots indicates whether the function
+	// accepts "..." arguments.
+	Dots bool
+
+	// Source is the printed source of the function.
+	Source string
+}
+
+// Arg is a formal argument of an R function.
+type Arg struct {
+	// Name is the name of the argument.
+	Name string
+
+	// Default is the deparsed default expression
+	// of the argument. It is only valid if
+	// HasDefault is true.
+	Default    string
+	HasDefault bool
+}
+
+// signatureCode is the R code used to obtain a function's formal arguments.
+// The result is a list of equal length vectors describing each argument.
+const signatureCode = `local({
+	f <- getExportedValue(%s, %s)
+	if (!is.function(f)) stop("not a function")
+	a <- formals(args(f))
+	list(
+		names = as.character(names(a)),
+		defaults = vapply(a, function(v) paste(deparse(v), collapse = "\n"), "", USE.NAMES = FALSE),
+		missing = vapply(a, function(v) is.symbol(v) && nchar(as.character(v)) == 0, TRUE, USE.NAMES = FALSE)
+	)
+})`
+
+// Signature returns a description of the R function f. The function's package
+// must be loadable by the server's R instance.
+func (s *Session) Signature(ctx context.Context, f Function) (*Signature, error) {
+	var formals struct {
+		Names    []string `json:"names"`
+		Defaults []string `json:"defaults"`
+		Missing  []bool   `json:"missing"`
+	}
+	err := s.evalJSON(ctx, fmt.Sprintf(signatureCode, rQuote(f.Package.Name), rQuote(f.Name)), &formals)
+	if err != nil {
+		return nil, err
+	}
+	if len(formals.Defaults) != len(formals.Names) || len(formals.Missing) != len(formals.Names) {
+		return nil, fmt.Errorf("arrgh: inconsistent formals for %s::%s", f.Package.Name, f.Name)
+	}
+
+	sig := Signature{Function: f}
+	for i, name := range formals.Names {
+		if name == "..." {
+			sig.Dots = true
+			continue
+		}
+		arg := Arg{Name: name, HasDefault: !formals.Missing[i]}
+		if arg.HasDefault {
+			arg.Default = formals.Defaults[i]
+		}
+		sig.Args = append(sig.Args, arg)
+	}
+
+	r, err := s.open(ctx, f.path()+"/print", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	sig.Source = string(src)
+
+	return &sig, nil
+}
+
+// Value is the JSON encoding of an R value.
+type Value json.RawMessage
+
+// Decode decodes the value into dst using encoding/json.
+func (v Value) Decode(dst interface{}) error {
+	return json.Unmarshal(v, dst)
+}
+
+// Invoke calls the R function f with the given named arguments, each encoded
+// as JSON, and returns the JSON encoding of the function's value. Arguments
+// with a nil value are not passed, so the function's default is used. The
+// URL parameters are interpreted by jsonlite as described for Post.
+func (s *Session) Invoke(ctx context.Context, f Function, args map[string]interface{}, params url.Values) (Value, error) {
+	m := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if v != nil {
+			m[k] = v
+		}
+	}
+	body, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.PostContext(ctx, f.path()+"/json", "application/json", params, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	err = checkResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return Value(b), nil
+}
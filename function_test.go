@@ -0,0 +1,94 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSignature(t *testing.T) {
+	const source = "function (x, ..., na.rm = FALSE) \nNULL\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ocpu/library/base/R/identity/json":
+			code := r.FormValue("x")
+			if !strings.Contains(code, `getExportedValue("base", "mean")`) {
+				http.Error(w, "unexpected code: "+code, http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"names":["x","...","na.rm"],"defaults":["","","FALSE"],"missing":[true,true,false]}`))
+		case "/ocpu/library/base/R/mean/print":
+			w.Write([]byte(source))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	s := testSession(t, srv.URL)
+
+	mean := Function{Package: Package{Library: SystemLibrary, Name: "base"}, Name: "mean"}
+	got, err := s.Signature(context.Background(), mean)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &Signature{
+		Function: mean,
+		Args: []Arg{
+			{Name: "x"},
+			{Name: "na.rm", Default: "FALSE", HasDefault: true},
+		},
+		Dots:   true,
+		Source: source,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected signature:\ngot: %+v\nwant:%+v", got, want)
+	}
+}
+
+func TestInvoke(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ocpu/library/stats/R/rnorm/json" {
+			http.NotFound(w, r)
+			return
+		}
+		var args map[string]float64
+		err := json.NewDecoder(r.Body).Decode(&args)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, ok := args["sd"]; ok {
+			http.Error(w, "unexpected nil argument", http.StatusBadRequest)
+			return
+		}
+		vals := make([]float64, int(args["n"]))
+		for i := range vals {
+			vals[i] = args["mean"]
+		}
+		json.NewEncoder(w).Encode(vals)
+	}))
+	defer srv.Close()
+	s := testSession(t, srv.URL)
+
+	rnorm := Function{Package: Package{Library: SystemLibrary, Name: "stats"}, Name: "rnorm"}
+	v, err := s.Invoke(context.Background(), rnorm, map[string]interface{}{"n": 3, "mean": 10, "sd": nil}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []float64
+	err = v.Decode(&got)
+	if err != nil {
+		t.Fatalf("unexpected error decoding value: %v", err)
+	}
+	if want := []float64{10, 10, 10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected value: got:%v want:%v", got, want)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+)
+
+// GraphicsFormat is an OpenCPU graphics output format.
+type GraphicsFormat string
+
+const (
+	PNG GraphicsFormat = "png"
+	SVG GraphicsFormat = "svg"
+	PDF GraphicsFormat = "pdf"
+)
+
+// Device holds graphics device parameters. Zero values are not sent to the
+// server, so the device's defaults are used.
+type Device struct {
+	// Width and Height are the dimensions of the
+	// graphic. They are in pixels for PNG and in
+	// inches for SVG and PDF.
+	Width, Height float64
+
+	// Res is the nominal resolution of a PNG
+	// graphic in pixels per inch.
+	Res int
+}
+
+// params returns the device parameters as URL parameters.
+func (d *Device) params() url.Values {
+	if d == nil {
+		return nil
+	}
+	v := make(url.Values)
+	if d.Width != 0 {
+		v.Set("width", strconv.FormatFloat(d.Width, 'g', -1, 64))
+	}
+	if d.Height != 0 {
+		v.Set("height", strconv.FormatFloat(d.Height, 'g', -1, 64))
+	}
+	if d.Res != 0 {
+		v.Set("res", strconv.Itoa(d.Res))
+	}
+	return v
+}
+
+// Graphics returns the names of the graphics produced in the session with
+// the given key, in the order they were created.
+func (s *Session) Graphics(ctx context.Context, key string) ([]string, error) {
+	names, err := s.list(ctx, sessionPath(key, "graphics"))
+	if err != nil {
+		return nil, err
+	}
+	// OpenCPU may list an alias for the final plot.
+	n := 0
+	for _, name := range names {
+		if name != "last" {
+			names[n] = name
+			n++
+		}
+	}
+	return names[:n], nil
+}
+
+// WriteGraphic renders the named graphic from the session with the given key
+// in the specified format and writes it to dst. If dev is not nil, it specifies
+// the parameters of the graphics device used to render the graphic.
+func (s *Session) WriteGraphic(ctx context.Context, dst io.Writer, key, name string, format GraphicsFormat, dev *Device) error {
+	switch format {
+	case PNG, SVG, PDF:
+	default:
+		return fmt.Errorf("arrgh: unknown graphics format: %q", format)
+	}
+	r, err := s.open(ctx, sessionPath(key, "graphics", name, string(format)), dev.params())
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(dst, r)
+	return err
+}
@@ -0,0 +1,57 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGraphics(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ocpu/tmp/x0123456789/graphics/":
+			w.Write([]byte("1\n2\nlast\n"))
+		case "/ocpu/tmp/x0123456789/graphics/2/svg":
+			query = r.URL.RawQuery
+			w.Write([]byte("<svg/>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	s := testSession(t, srv.URL)
+	ctx := context.Background()
+
+	names, err := s.Graphics(ctx, "x0123456789")
+	if err != nil {
+		t.Fatalf("unexpected error listing graphics: %v", err)
+	}
+	if want := []string{"1", "2"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("unexpected graphics: got:%q want:%q", names, want)
+	}
+
+	var buf bytes.Buffer
+	err = s.WriteGraphic(ctx, &buf, "x0123456789", "2", SVG, &Device{Width: 7.5, Height: 5})
+	if err != nil {
+		t.Fatalf("unexpected error writing graphic: %v", err)
+	}
+	if buf.String() != "<svg/>" {
+		t.Errorf("unexpected graphic: got:%q", buf.String())
+	}
+	if want := "height=5&width=7.5"; query != want {
+		t.Errorf("unexpected device parameters: got:%q want:%q", query, want)
+	}
+
+	err = s.WriteGraphic(ctx, &buf, "x0123456789", "2", "bmp", nil)
+	if err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+)
+
+// Info describes the R environment of an OpenCPU server, as reported by
+// R's sessionInfo function.
+type Info struct {
+	// RVersion and RDate are the version and
+	// release date of R, for example "3.4.1"
+	// and "2017-06-30".
+	RVersion string
+	RDate    string
+
+	// Platform is the platform R was built for
+	// and RunningUnder is the operating system
+	// it is running on.
+	Platform     string
+	RunningUnder string
+
+	// BLAS and LAPACK are the paths of the linear
+	// algebra libraries used by R. They are
+	// empty if not reported.
+	BLAS   string
+	LAPACK string
+
+	// Locale holds the locale settings of the
+	// R session, for example "LC_CTYPE=en_US.UTF-8".
+	Locale []string
+
+	// TimeZone is the time zone of the R session
+	// if it is reported.
+	TimeZone string
+
+	// BasePackages holds the names of the attached
+	// base packages. Attached and Loaded hold the
+	// other attached packages and the packages
+	// loaded via a namespace but not attached.
+	BasePackages []string
+	Attached     []PackageVersion
+	Loaded       []PackageVersion
+
+	// OpenCPUVersion is the version of OpenCPU
+	// serving the session, if it is known.
+	OpenCPUVersion string
+
+	// Text is the unparsed sessionInfo text.
+	Text string
+}
+
+// PackageVersion is an R package name and version.
+type PackageVersion struct {
+	Name    string
+	Version string
+}
+
+// ParseInfo parses the text output of R's sessionInfo function read from r.
+// Unrecognised lines are ignored.
+func ParseInfo(r io.Reader) (*Info, error) {
+	var (
+		info    Info
+		text    strings.Builder
+		section string
+	)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		text.WriteString(line)
+		text.WriteByte('\n')
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			section = ""
+		case strings.HasPrefix(trimmed, "R version "):
+			f := strings.Fields(strings.TrimPrefix(trimmed, "R version "))
+			if len(f) > 0 {
+				info.RVersion = f[0]
+			}
+			if len(f) > 1 {
+				info.RDate = strings.Trim(f[1], "()")
+			}
+		case strings.HasPrefix(trimmed, "Platform:"):
+			info.Platform = value(trimmed)
+		case strings.HasPrefix(trimmed, "Running under:"):
+			info.RunningUnder = value(trimmed)
+		case strings.HasPrefix(trimmed, "BLAS/LAPACK:"):
+			// R ≥4.1 may report a single shared library.
+			v := libraryPath(value(trimmed))
+			info.BLAS, info.LAPACK = v, v
+		case strings.HasPrefix(trimmed, "BLAS:"):
+			info.BLAS = libraryPath(value(trimmed))
+		case strings.HasPrefix(trimmed, "LAPACK:"):
+			info.LAPACK = libraryPath(value(trimmed))
+		case strings.HasPrefix(trimmed, "time zone:"):
+			info.TimeZone = value(trimmed)
+		case strings.HasSuffix(trimmed, ":") && !strings.HasPrefix(trimmed, "["):
+			section = strings.TrimSuffix(trimmed, ":")
+		case strings.HasPrefix(trimmed, "["):
+			items := vectorItems(trimmed)
+			switch section {
+			case "locale":
+				info.Locale = append(info.Locale, items...)
+			case "attached base packages":
+				info.BasePackages = append(info.BasePackages, items...)
+			case "other attached packages":
+				info.Attached = append(info.Attached, packageVersions(items)...)
+			case "loaded via a namespace (and not attached)":
+				info.Loaded = append(info.Loaded, packageVersions(items)...)
+			}
+		default:
+			if section == "locale" {
+				// A single unlisted locale setting.
+				info.Locale = append(info.Locale, trimmed)
+			}
+		}
+	}
+	err := sc.Err()
+	if err != nil {
+		return nil, err
+	}
+	info.Text = text.String()
+	for _, pkgs := range [][]PackageVersion{info.Attached, info.Loaded} {
+		for _, p := range pkgs {
+			if p.Name == "opencpu" {
+				info.OpenCPUVersion = p.Version
+			}
+		}
+	}
+	return &info, nil
+}
+
+// value returns the text following the first colon in s.
+func value(s string) string {
+	i := strings.Index(s, ":")
+	return strings.TrimSpace(s[i+1:])
+}
+
+// libraryPath returns the path of a linear algebra library reported
+// by sessionInfo. R ≥4.2 follows the path of LAPACK with its version,
+// separated by a semicolon.
+func libraryPath(s string) string {
+	if i := strings.Index(s, ";"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s
+}
+
+// vectorItems returns the elements of a line of a printed R vector,
+// dropping the leading index.
+func vectorItems(s string) []string {
+	f := strings.Fields(s)
+	if len(f) != 0 && strings.HasPrefix(f[0], "[") && strings.HasSuffix(f[0], "]") {
+		f = f[1:]
+	}
+	return f
+}
+
+// packageVersions returns the package names and versions from
+// name_version items.
+func packageVersions(items []string) []PackageVersion {
+	p := make([]PackageVersion, len(items))
+	for i, it := range items {
+		name, version := it, ""
+		if j := strings.Index(it, "_"); j >= 0 {
+			name, version = it[:j], it[j+1:]
+		}
+		p[i] = PackageVersion{Name: name, Version: version}
+	}
+	return p
+}
+
+// Info returns the R environment information of the server.
+func (s *Session) Info(ctx context.Context) (*Info, error) {
+	return s.info(ctx, "info")
+}
+
+// SessionInfo returns the R environment information recorded for the
+// session with the given key.
+func (s *Session) SessionInfo(ctx context.Context, key string) (*Info, error) {
+	return s.info(ctx, sessionPath(key, "info"))
+}
+
+func (s *Session) info(ctx context.Context, path string) (*Info, error) {
+	resp, err := s.GetContext(ctx, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = checkResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	info, err := ParseInfo(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if v := resp.Header.Get("X-Ocpu-Version"); v != "" {
+		info.OpenCPUVersion = v
+	}
+	return info, nil
+}
@@ -0,0 +1,163 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sessionInfo = `R version 3.4.1 (2017-06-30)
+Platform: x86_64-pc-linux-gnu (64-bit)
+Running under: Ubuntu 16.04.2 LTS
+
+Matrix products: default
+BLAS: /usr/lib/openblas-base/libblas.so.3
+LAPACK: /usr/lib/libopenblasp-r0.2.18.so
+
+locale:
+ [1] LC_CTYPE=en_US.UTF-8    LC_NUMERIC=C            LC_TIME=en_US.UTF-8    
+ [4] LC_COLLATE=en_US.UTF-8  LC_MONETARY=en_US.UTF-8 LC_MESSAGES=C          
+
+attached base packages:
+[1] stats     graphics  grDevices utils     datasets  methods   base     
+
+other attached packages:
+[1] opencpu_2.0.3.1
+
+loaded via a namespace (and not attached):
+ [1] Rcpp_0.12.11     lattice_0.20-35  mime_0.5        
+`
+
+var parseInfoTests = []struct {
+	text string
+	want Info
+}{
+	{
+		text: sessionInfo,
+		want: Info{
+			RVersion:     "3.4.1",
+			RDate:        "2017-06-30",
+			Platform:     "x86_64-pc-linux-gnu (64-bit)",
+			RunningUnder: "Ubuntu 16.04.2 LTS",
+			BLAS:         "/usr/lib/openblas-base/libblas.so.3",
+			LAPACK:       "/usr/lib/libopenblasp-r0.2.18.so",
+			Locale: []string{
+				"LC_CTYPE=en_US.UTF-8", "LC_NUMERIC=C", "LC_TIME=en_US.UTF-8",
+				"LC_COLLATE=en_US.UTF-8", "LC_MONETARY=en_US.UTF-8", "LC_MESSAGES=C",
+			},
+			BasePackages: []string{"stats", "graphics", "grDevices", "utils", "datasets", "methods", "base"},
+			Attached:     []PackageVersion{{"opencpu", "2.0.3.1"}},
+			Loaded:       []PackageVersion{{"Rcpp", "0.12.11"}, {"lattice", "0.20-35"}, {"mime", "0.5"}},
+
+			OpenCPUVersion: "2.0.3.1",
+		},
+	},
+	{
+		text: `R version 4.3.1 (2023-06-16)
+Platform: x86_64-pc-linux-gnu (64-bit)
+Running under: Debian GNU/Linux 12 (bookworm)
+
+Matrix products: default
+BLAS/LAPACK: /usr/lib/x86_64-linux-gnu/openblas-pthread/libopenblasp-r0.3.21.so;  LAPACK version 3.11.0
+
+locale:
+[1] C
+
+time zone: Etc/UTC
+tzcode source: system (glibc)
+
+attached base packages:
+[1] base
+`,
+		want: Info{
+			RVersion:     "4.3.1",
+			RDate:        "2023-06-16",
+			Platform:     "x86_64-pc-linux-gnu (64-bit)",
+			RunningUnder: "Debian GNU/Linux 12 (bookworm)",
+			BLAS:         "/usr/lib/x86_64-linux-gnu/openblas-pthread/libopenblasp-r0.3.21.so",
+			LAPACK:       "/usr/lib/x86_64-linux-gnu/openblas-pthread/libopenblasp-r0.3.21.so",
+			Locale:       []string{"C"},
+			TimeZone:     "Etc/UTC",
+			BasePackages: []string{"base"},
+		},
+	},
+	{
+		text: `R version 4.2.2 (2022-10-31)
+Platform: x86_64-pc-linux-gnu (64-bit)
+Running under: Ubuntu 22.04.1 LTS
+
+Matrix products: default
+BLAS:   /usr/lib/x86_64-linux-gnu/blas/libblas.so.3.10.0
+LAPACK: /usr/lib/x86_64-linux-gnu/lapack/liblapack.so.3.10.0;  LAPACK version 3.10.0
+
+locale:
+[1] C
+
+attached base packages:
+[1] base
+`,
+		want: Info{
+			RVersion:     "4.2.2",
+			RDate:        "2022-10-31",
+			Platform:     "x86_64-pc-linux-gnu (64-bit)",
+			RunningUnder: "Ubuntu 22.04.1 LTS",
+			BLAS:         "/usr/lib/x86_64-linux-gnu/blas/libblas.so.3.10.0",
+			LAPACK:       "/usr/lib/x86_64-linux-gnu/lapack/liblapack.so.3.10.0",
+			Locale:       []string{"C"},
+			BasePackages: []string{"base"},
+		},
+	},
+}
+
+func TestParseInfo(t *testing.T) {
+	for _, test := range parseInfoTests {
+		got, err := ParseInfo(strings.NewReader(test.text))
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if got.Text != test.text {
+			t.Errorf("unexpected text: got:%q want:%q", got.Text, test.text)
+		}
+		got.Text = ""
+		if !reflect.DeepEqual(*got, test.want) {
+			t.Errorf("unexpected info:\ngot: %+v\nwant:%+v", *got, test.want)
+		}
+	}
+}
+
+func TestInfo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ocpu/info", "/ocpu/tmp/x0123456789/info":
+			w.Header().Set("X-Ocpu-Version", "2.2.9")
+			w.Write([]byte(sessionInfo))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	s := testSession(t, srv.URL)
+
+	info, err := s.Info(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.RVersion != "3.4.1" || info.OpenCPUVersion != "2.2.9" {
+		t.Errorf("unexpected info: got:%+v", info)
+	}
+	info, err = s.SessionInfo(context.Background(), "x0123456789")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.RVersion != "3.4.1" {
+		t.Errorf("unexpected session info: got:%+v", info)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	pth "path"
+)
+
+// Library is the path of an OpenCPU package library relative to the
+// OpenCPU root. Not all servers provide all libraries.
+type Library string
+
+const (
+	// SystemLibrary holds the packages installed on the server.
+	SystemLibrary Library = "library"
+
+	// CRANLibrary holds packages from CRAN.
+	CRANLibrary Library = "cran"
+
+	// BiocLibrary holds packages from Bioconductor.
+	BiocLibrary Library = "bioc"
+)
+
+// GitHubLibrary returns the library holding the R packages in the
+// GitHub account of the given user.
+func GitHubLibrary(user string) Library {
+	return Library(pth.Join("github", user))
+}
+
+// UserLibrary returns the library of packages installed by the named
+// user on the server.
+func UserLibrary(name string) Library {
+	return Library(pth.Join("user", name, "library"))
+}
+
+// Package is an R package in an OpenCPU library.
+type Package struct {
+	Library Library
+	Name    string
+}
+
+// path returns the path of elem within the package.
+func (p Package) path(elem ...string) string {
+	return pth.Join(append([]string{string(p.Library), p.Name}, elem...)...)
+}
+
+// Packages returns the packages available in the given library.
+func (s *Session) Packages(ctx context.Context, lib Library) ([]Package, error) {
+	names, err := s.list(ctx, string(lib))
+	if err != nil {
+		return nil, err
+	}
+	pkgs := make([]Package, len(names))
+	for i, n := range names {
+		pkgs[i] = Package{Library: lib, Name: n}
+	}
+	return pkgs, nil
+}
+
+// Function is an exported R object, usually a function, in a package.
+type Function struct {
+	Package Package
+	Name    string
+}
+
+// path returns the OpenCPU path of the function.
+func (f Function) path() string {
+	return f.Package.path("R", f.Name)
+}
+
+// Dataset is a dataset in a package.
+type Dataset struct {
+	Package Package
+	Name    string
+}
+
+// Topic is a manual page topic in a package.
+type Topic struct {
+	Package Package
+	Name    string
+}
+
+// Asset is a file in the www directory of a package.
+type Asset struct {
+	Package Package
+	Path    string
+}
+
+// Functions returns the exported R objects of the package.
+func (s *Session) Functions(ctx context.Context, p Package) ([]Function, error) {
+	names, err := s.list(ctx, p.path("R"))
+	if err != nil {
+		return nil, err
+	}
+	f := make([]Function, len(names))
+	for i, n := range names {
+		f[i] = Function{Package: p, Name: n}
+	}
+	return f, nil
+}
+
+// Datasets returns the datasets provided by the package.
+func (s *Session) Datasets(ctx context.Context, p Package) ([]Dataset, error) {
+	names, err := s.list(ctx, p.path("data"))
+	if err != nil {
+		return nil, err
+	}
+	d := make([]Dataset, len(names))
+	for i, n := range names {
+		d[i] = Dataset{Package: p, Name: n}
+	}
+	return d, nil
+}
+
+// Topics returns the manual page topics of the package.
+func (s *Session) Topics(ctx context.Context, p Package) ([]Topic, error) {
+	names, err := s.list(ctx, p.path("man"))
+	if err != nil {
+		return nil, err
+	}
+	t := make([]Topic, len(names))
+	for i, n := range names {
+		t[i] = Topic{Package: p, Name: n}
+	}
+	return t, nil
+}
+
+// Assets returns the files in the www directory of the package.
+func (s *Session) Assets(ctx context.Context, p Package) ([]Asset, error) {
+	names, err := s.list(ctx, p.path("www"))
+	if err != nil {
+		return nil, err
+	}
+	a := make([]Asset, len(names))
+	for i, n := range names {
+		a[i] = Asset{Package: p, Path: n}
+	}
+	return a, nil
+}
@@ -0,0 +1,93 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestLibrary(t *testing.T) {
+	listings := map[string]string{
+		"/ocpu/library/":                      "base\nstats\n",
+		"/ocpu/user/alice/library/":           "mypkg\n",
+		"/ocpu/library/stats/R/":              "rnorm\nsd\n",
+		"/ocpu/library/datasets/data/":        "cars\niris\n",
+		"/ocpu/library/stats/man/":            "Normal\nsd\n",
+		"/ocpu/user/alice/library/mypkg/www/": "index.html\napp.js\n",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l, ok := listings[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(l))
+	}))
+	defer srv.Close()
+	s := testSession(t, srv.URL)
+	ctx := context.Background()
+
+	pkgs, err := s.Packages(ctx, SystemLibrary)
+	if err != nil {
+		t.Fatalf("unexpected error listing packages: %v", err)
+	}
+	base := Package{Library: SystemLibrary, Name: "base"}
+	stats := Package{Library: SystemLibrary, Name: "stats"}
+	if want := []Package{base, stats}; !reflect.DeepEqual(pkgs, want) {
+		t.Errorf("unexpected packages: got:%v want:%v", pkgs, want)
+	}
+
+	alice := UserLibrary("alice")
+	pkgs, err = s.Packages(ctx, alice)
+	if err != nil {
+		t.Fatalf("unexpected error listing user packages: %v", err)
+	}
+	mypkg := Package{Library: alice, Name: "mypkg"}
+	if want := []Package{mypkg}; !reflect.DeepEqual(pkgs, want) {
+		t.Errorf("unexpected user packages: got:%v want:%v", pkgs, want)
+	}
+
+	fns, err := s.Functions(ctx, stats)
+	if err != nil {
+		t.Fatalf("unexpected error listing functions: %v", err)
+	}
+	if want := []Function{{stats, "rnorm"}, {stats, "sd"}}; !reflect.DeepEqual(fns, want) {
+		t.Errorf("unexpected functions: got:%v want:%v", fns, want)
+	}
+
+	datasets := Package{Library: SystemLibrary, Name: "datasets"}
+	data, err := s.Datasets(ctx, datasets)
+	if err != nil {
+		t.Fatalf("unexpected error listing datasets: %v", err)
+	}
+	if want := []Dataset{{datasets, "cars"}, {datasets, "iris"}}; !reflect.DeepEqual(data, want) {
+		t.Errorf("unexpected datasets: got:%v want:%v", data, want)
+	}
+
+	topics, err := s.Topics(ctx, stats)
+	if err != nil {
+		t.Fatalf("unexpected error listing topics: %v", err)
+	}
+	if want := []Topic{{stats, "Normal"}, {stats, "sd"}}; !reflect.DeepEqual(topics, want) {
+		t.Errorf("unexpected topics: got:%v want:%v", topics, want)
+	}
+
+	assets, err := s.Assets(ctx, mypkg)
+	if err != nil {
+		t.Fatalf("unexpected error listing assets: %v", err)
+	}
+	if want := []Asset{{mypkg, "index.html"}, {mypkg, "app.js"}}; !reflect.DeepEqual(assets, want) {
+		t.Errorf("unexpected assets: got:%v want:%v", assets, want)
+	}
+
+	_, err = s.Functions(ctx, Package{Library: CRANLibrary, Name: "missing"})
+	if _, ok := err.(*Error); !ok {
+		t.Errorf("expected *Error for missing package: got:%v", err)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// ManFormat is an R documentation output format.
+type ManFormat string
+
+const (
+	ManText     ManFormat = "text"
+	ManHTML     ManFormat = "html"
+	ManMarkdown ManFormat = "md"
+	ManPDF      ManFormat = "pdf"
+)
+
+// Manual returns a reader for the R documentation of the topic rendered in
+// the specified format. The list of a package's topics is available from
+// Topics. The returned reader must be closed after use.
+func (s *Session) Manual(ctx context.Context, t Topic, format ManFormat) (io.ReadCloser, error) {
+	switch format {
+	case ManText, ManHTML, ManMarkdown, ManPDF:
+	default:
+		return nil, fmt.Errorf("arrgh: unknown manual format: %q", format)
+	}
+	return s.open(ctx, t.Package.path("man", t.Name, string(format)), nil)
+}
@@ -0,0 +1,50 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+)
+
+func TestManual(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dir, format := path.Split(r.URL.Path)
+		if dir != "/ocpu/library/stats/man/rnorm/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("rnorm as " + format))
+	}))
+	defer srv.Close()
+	s := testSession(t, srv.URL)
+
+	topic := Topic{Package: Package{Library: SystemLibrary, Name: "stats"}, Name: "rnorm"}
+	for _, format := range []ManFormat{ManText, ManHTML, ManMarkdown, ManPDF} {
+		r, err := s.Manual(context.Background(), topic, format)
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", format, err)
+			continue
+		}
+		got, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Errorf("unexpected error reading %s: %v", format, err)
+			continue
+		}
+		if want := "rnorm as " + string(format); string(got) != want {
+			t.Errorf("unexpected manual: got:%q want:%q", got, want)
+		}
+	}
+
+	_, err := s.Manual(context.Background(), topic, "rtf")
+	if err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	pth "path"
+	"time"
+)
+
+// Host is an OpenCPU server used by a multi-host session.
+type Host struct {
+	// URL is the URL of the server, for
+	// example "http://opencpu1.example.com".
+	URL string
+
+	// Weight is the relative share of new
+	// calls sent to the host by Weighted
+	// selection. A zero Weight is treated
+	// as one.
+	Weight int
+}
+
+// Selection is a strategy for choosing the host for a new call.
+type Selection int
+
+const (
+	// RoundRobin sends new calls to each host in turn.
+	RoundRobin Selection = iota
+
+	// Weighted sends new calls to hosts in proportion
+	// to their weights.
+	Weighted
+)
+
+// hostRetry is the time a host that has failed is avoided for new calls.
+const hostRetry = 10 * time.Second
+
+// NewMultiHostSession returns a session that distributes requests among the
+// OpenCPU servers at the given hosts, which must all use the same API root.
+// The root of the OpenCPU API is set to "/ocpu" if it is left empty.
+//
+// Since the servers do not share session storage, the session records the
+// host that issued each session key, and requests that refer to a key, in
+// their path, parameters or body, are sent to that host. Other requests are
+// sent to a host chosen by the selection strategy sel. If such a request
+// cannot be sent to the host, or the host refuses it with a 503 Service
+// Unavailable response, the request is retried on the other hosts and the
+// failed host is avoided for new calls for a short time. Since the host may
+// have handled them, requests other than GET that fail after reaching the
+// host, and requests that receive other error responses, including 502 Bad
+// Gateway and 504 Gateway Timeout, are not retried. Request bodies larger
+// than 1MiB and bodies of unknown length are not inspected for keys and
+// their requests are not retried. Keys are remembered for 24 hours after
+// they are issued, and at most 65536 keys are remembered.
+//
+// Connection to the hosts is tested before returning by requesting their
+// server information. If no host responds successfully within the timeout,
+// a nil session and an error are returned.
+func NewMultiHostSession(hosts []Host, root string, sel Selection, timeout time.Duration) (*Session, error) {
+	if len(hosts) == 0 {
+		return nil, errors.New("arrgh: no hosts")
+	}
+	if root == "" {
+		root = "ocpu"
+	}
+	r := &router{
+		root:      pth.Join("/", root),
+		transport: http.DefaultTransport,
+		keys:      make(map[string]*issue),
+		failed: func(b *backend) {
+			b.retry = time.Now().Add(hostRetry)
+		},
+	}
+	switch sel {
+	case RoundRobin:
+		r.order = roundRobin()
+	case Weighted:
+		r.order = weighted
+	default:
+		return nil, errors.New("arrgh: invalid host selection")
+	}
+	for _, h := range hosts {
+		u, err := url.Parse(h.URL)
+		if err != nil {
+			return nil, err
+		}
+		u.Path = pth.Join("/", u.Path, root)
+		w := h.Weight
+		if w < 0 {
+			return nil, errors.New("arrgh: negative host weight")
+		}
+		if w == 0 {
+			w = 1
+		}
+		r.backends = append(r.backends, &backend{base: u, weight: w})
+	}
+
+	start := time.Now()
+	for {
+		var ok bool
+		for _, b := range r.backends {
+			if ping(b.base.String()) {
+				b.retry = time.Time{}
+				ok = true
+			} else {
+				b.retry = time.Now().Add(hostRetry)
+			}
+		}
+		if ok {
+			return newRoutedSession(r), nil
+		} else if timeout > 0 && time.Now().Sub(start) > timeout {
+			return nil, errors.New("arrgh: no host available")
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// available splits backends into those that are preferred for new calls
+// and those that have recently failed.
+func available(backends []*backend) (up, down []*backend) {
+	now := time.Now()
+	for _, b := range backends {
+		if now.Before(b.retry) {
+			down = append(down, b)
+		} else {
+			up = append(up, b)
+		}
+	}
+	return up, down
+}
+
+// roundRobin returns an order function that starts with each available
+// backend in turn.
+func roundRobin() func([]*backend) []*backend {
+	var next int
+	return func(backends []*backend) []*backend {
+		up, down := available(backends)
+		if len(up) == 0 {
+			return down
+		}
+		i := next % len(up)
+		next++
+		order := make([]*backend, 0, len(backends))
+		order = append(order, up[i:]...)
+		order = append(order, up[:i]...)
+		return append(order, down...)
+	}
+}
+
+// weighted orders backends by smooth weighted round-robin selection,
+// with the selected available backend first.
+func weighted(backends []*backend) []*backend {
+	up, down := available(backends)
+	if len(up) == 0 {
+		return down
+	}
+	var (
+		total int
+		best  int
+	)
+	for i, b := range up {
+		b.current += b.weight
+		total += b.weight
+		if b.current > up[best].current {
+			best = i
+		}
+	}
+	up[best].current -= total
+	order := make([]*backend, 0, len(backends))
+	order = append(order, up[best])
+	order = append(order, up[:best]...)
+	order = append(order, up[best+1:]...)
+	return append(order, down...)
+}
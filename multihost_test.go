@@ -0,0 +1,185 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kortschak/arrgh/ocputest"
+)
+
+func TestMultiHostSession(t *testing.T) {
+	var hosts []Host
+	var srvs []*ocputest.Server
+	for i := 0; i < 3; i++ {
+		srv := ocputest.NewServer()
+		defer srv.Close()
+		srvs = append(srvs, srv)
+		hosts = append(hosts, Host{URL: srv.URL})
+	}
+	s, err := NewMultiHostSession(hosts, "", RoundRobin, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error opening session: %v", err)
+	}
+	r := s.client.Transport.(*router)
+	ctx := context.Background()
+
+	// Keys are only valid on the host that issued them, so chained
+	// calls and gets fail unless they are routed there.
+	issuers := make(map[*backend]bool)
+	for i := 0; i < len(hosts); i++ {
+		x, err := s.Call(ctx, "library/base/R/identity", "application/json", nil, strings.NewReader(`{"x":[1,2,3,4]}`))
+		if err != nil {
+			t.Fatalf("unexpected error calling identity: %v", err)
+		}
+		issuers[r.issuer(x.Key)] = true
+		res, err := s.Call(ctx, "library/base/R/sum", "application/x-www-form-urlencoded", nil, strings.NewReader("x="+x.Key))
+		if err != nil {
+			t.Fatalf("unexpected error calling sum with session key: %v", err)
+		}
+		if r.issuer(res.Key) != r.issuer(x.Key) {
+			t.Errorf("chained call not sent to issuing host")
+		}
+		rc, err := s.Object(ctx, "tmp/"+res.Key+"/R/.val", JSON, nil)
+		if err != nil {
+			t.Fatalf("unexpected error getting sum: %v", err)
+		}
+		var sum []float64
+		err = json.NewDecoder(rc).Decode(&sum)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("unexpected error decoding sum: %v", err)
+		}
+		if !reflect.DeepEqual(sum, []float64{10}) {
+			t.Errorf("unexpected sum: got:%v want:[10]", sum)
+		}
+	}
+	if len(issuers) != len(hosts) {
+		t.Errorf("calls not distributed across hosts: used %d of %d", len(issuers), len(hosts))
+	}
+
+	x, err := s.Call(ctx, "library/base/R/identity", "application/json", nil, strings.NewReader(`{"x":1}`))
+	if err != nil {
+		t.Fatalf("unexpected error calling identity: %v", err)
+	}
+	lost := r.issuer(x.Key)
+	for _, srv := range srvs {
+		if srv.URL+"/ocpu" == lost.base.String() {
+			srv.Close()
+		}
+	}
+
+	// Calls without references fail over to the remaining hosts.
+	for i := 0; i < len(hosts); i++ {
+		res, err := s.Call(ctx, "library/base/R/identity", "application/json", nil, strings.NewReader(`{"x":1}`))
+		if err != nil {
+			t.Fatalf("unexpected error calling identity after host failure: %v", err)
+		}
+		if r.issuer(res.Key) == lost {
+			t.Error("call sent to failed host")
+		}
+	}
+	if !time.Now().Before(lost.retry) {
+		t.Error("failed host not marked for retry")
+	}
+
+	// Calls with references to the lost host's keys are not.
+	_, err = s.Call(ctx, "library/base/R/sum", "application/x-www-form-urlencoded", nil, strings.NewReader("x="+x.Key))
+	if err == nil {
+		t.Error("expected error for call referring to key on failed host")
+	}
+}
+
+func TestMultiHostUnavailable(t *testing.T) {
+	busy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPost {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+		}
+	}))
+	defer busy.Close()
+	srv := ocputest.NewServer()
+	defer srv.Close()
+
+	s, err := NewMultiHostSession([]Host{{URL: busy.URL}, {URL: srv.URL}}, "", RoundRobin, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error opening session: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		_, err = s.Call(context.Background(), "library/base/R/identity", "application/json", nil, strings.NewReader(`{"x":1}`))
+		if err != nil {
+			t.Errorf("unexpected error calling identity: %v", err)
+		}
+	}
+}
+
+func TestMultiHostNoRetry(t *testing.T) {
+	var posts int
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPost {
+			posts++
+			http.Error(w, "upstream timed out", http.StatusGatewayTimeout)
+		}
+	}))
+	defer gateway.Close()
+	srv := ocputest.NewServer()
+	defer srv.Close()
+
+	s, err := NewMultiHostSession([]Host{{URL: gateway.URL}, {URL: srv.URL}}, "", RoundRobin, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error opening session: %v", err)
+	}
+	// The call may have been handled by the host behind the
+	// gateway, so it is not sent again.
+	_, err = s.Call(context.Background(), "library/base/R/identity", "application/json", nil, strings.NewReader(`{"x":1}`))
+	var e *Error
+	if !errors.As(err, &e) || e.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("unexpected error calling identity: got:%v want:%d status", err, http.StatusGatewayTimeout)
+	}
+	if posts != 1 {
+		t.Errorf("unexpected number of calls to gateway: got:%d want:1", posts)
+	}
+	_, err = s.Call(context.Background(), "library/base/R/identity", "application/json", nil, strings.NewReader(`{"x":1}`))
+	if err != nil {
+		t.Errorf("unexpected error calling identity: %v", err)
+	}
+}
+
+func TestMultiHostPing(t *testing.T) {
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "broken", http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	_, err := NewMultiHostSession([]Host{{URL: broken.URL}}, "", RoundRobin, time.Millisecond)
+	if err == nil {
+		t.Error("expected error for host responding with error status")
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	a := &backend{weight: 3}
+	b := &backend{weight: 1}
+	c := &backend{weight: 1, retry: time.Now().Add(time.Hour)}
+	var got []*backend
+	for i := 0; i < 8; i++ {
+		order := weighted([]*backend{a, b, c})
+		if order[len(order)-1] != c {
+			t.Errorf("failed backend not last")
+		}
+		got = append(got, order[0])
+	}
+	want := []*backend{a, a, b, a, a, a, b, a}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected selection: got:%v want:%v", got, want)
+	}
+}
@@ -0,0 +1,293 @@
+// Copyright ©2014 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Params is a collection of parameter names and values to be passed using Multipart.
+type Params map[string]string
+
+// NamedReader allows an io.Reader to be passed as a named data file object.
+type NamedReader interface {
+	io.Reader
+	Name() string
+}
+
+// Params is a collection of parameter names and file objects to be passed using Multipart.
+type Files map[string]NamedReader
+
+// Multipart constructs a MIME multipart body and associated content type from the
+// provided parameters and files. Files are written before parameters, each in order
+// of their names, and files are given the content type "application/octet-stream".
+// MultipartParts allows the order and content types of parts to be specified.
+func Multipart(parameters Params, files Files) (content string, body io.Reader, err error) {
+	return MultipartParts(parts(parameters, files)...)
+}
+
+// MultipartParts constructs a MIME multipart body and associated content type from
+// the provided parts. The parts are written to the body in order.
+func MultipartParts(parts ...Part) (content string, body io.Reader, err error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	err = writeParts(w, parts, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	return w.FormDataContentType(), &buf, nil
+}
+
+// StreamMultipart constructs a MIME multipart body and associated content type from
+// the provided parameters and files. Unlike Multipart, the contents of the files are
+// not buffered, but are copied into the body as it is read.
+//
+// If the sizes of all the files can be determined, the Size method of the returned
+// body reports the length of the body, otherwise it returns -1. A file's size can be
+// determined if it has a Len method, as *bytes.Reader and *strings.Reader do, or if
+// it is a regular file with a Stat method, as *os.File does. Errors encountered while
+// copying a file are returned by the body's Read method.
+//
+// The returned body must either be read to EOF or closed.
+func StreamMultipart(parameters Params, files Files) (content string, body *MultipartBody, err error) {
+	return StreamMultipartParts(parts(parameters, files)...)
+}
+
+// StreamMultipartParts is the streaming equivalent of MultipartParts. The
+// returned body behaves as described for StreamMultipart.
+func StreamMultipartParts(parts ...Part) (content string, body *MultipartBody, err error) {
+	pr, pw := io.Pipe()
+	w := multipart.NewWriter(pw)
+	size, sizes, err := multipartSize(w.Boundary(), parts)
+	if err != nil {
+		return "", nil, err
+	}
+	go func() {
+		pw.CloseWithError(writeParts(w, parts, sizes))
+	}()
+
+	return w.FormDataContentType(), &MultipartBody{pr: pr, size: size}, nil
+}
+
+// MultipartBody is a streaming MIME multipart body returned by StreamMultipart.
+type MultipartBody struct {
+	pr   *io.PipeReader
+	size int64
+}
+
+// Read implements the io.Reader interface.
+func (b *MultipartBody) Read(p []byte) (int, error) { return b.pr.Read(p) }
+
+// Close closes the body, terminating the copying of file contents.
+func (b *MultipartBody) Close() error { return b.pr.Close() }
+
+// Size returns the length of the body in bytes, or -1 if it is not known.
+func (b *MultipartBody) Size() int64 { return b.size }
+
+// Part is a single part of a MIME multipart body. Parts are constructed
+// using Field, JSONField, File, BytesFile, StringFile and FSFile.
+type Part struct {
+	name     string
+	filename string
+	typ      string
+	value    string
+
+	// r holds the contents of a file part.
+	// It is nil for parameter parts.
+	r io.Reader
+}
+
+// Field returns a parameter part with the given name and value.
+func Field(name, value string) Part {
+	return Part{name: name, value: value}
+}
+
+// JSONField returns a parameter part with the given name and the JSON
+// encoding of v as its value.
+func JSONField(name string, v interface{}) (Part, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return Part{}, err
+	}
+	return Part{name: name, value: string(b)}, nil
+}
+
+// File returns a file part with the given name holding the contents of f.
+// The file name of the part is the base of f's name. If contentType is empty,
+// "application/octet-stream" is used.
+func File(name string, f NamedReader, contentType string) Part {
+	return Part{name: name, filename: filepath.Base(f.Name()), typ: contentType, r: f}
+}
+
+// BytesFile returns a file part with the given name and file name holding the
+// contents of b. If contentType is empty, "application/octet-stream" is used.
+func BytesFile(name, filename string, b []byte, contentType string) Part {
+	return Part{name: name, filename: filename, typ: contentType, r: bytes.NewReader(b)}
+}
+
+// StringFile returns a file part with the given name and file name holding the
+// contents of s. If contentType is empty, "application/octet-stream" is used.
+func StringFile(name, filename, s, contentType string) Part {
+	return Part{name: name, filename: filename, typ: contentType, r: strings.NewReader(s)}
+}
+
+// FSFile returns a file part with the given name holding the contents of f.
+// The file name of the part is the name reported by f's Stat method. If
+// contentType is empty, "application/octet-stream" is used.
+func FSFile(name string, f fs.File, contentType string) (Part, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return Part{}, err
+	}
+	return Part{name: name, filename: fi.Name(), typ: contentType, r: f}, nil
+}
+
+// parts returns the parts corresponding to the provided parameters
+// and files, files first, each sorted by name.
+func parts(parameters Params, files Files) []Part {
+	p := make([]Part, 0, len(parameters)+len(files))
+	for label, f := range files {
+		p = append(p, File(label, f, ""))
+	}
+	sort.Slice(p, func(i, j int) bool { return p[i].name < p[j].name })
+	n := len(p)
+	for k, v := range parameters {
+		p = append(p, Field(k, v))
+	}
+	sort.Slice(p[n:], func(i, j int) bool { return p[n+i].name < p[n+j].name })
+	return p
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// create creates a new multipart section for the file part p in w.
+func (p Part) create(w *multipart.Writer) (io.Writer, error) {
+	typ := p.typ
+	if typ == "" {
+		typ = "application/octet-stream"
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition",
+		fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(p.name), quoteEscaper.Replace(p.filename)))
+	h.Set("Content-Type", typ)
+	return w.CreatePart(h)
+}
+
+// writeParts writes the parts to w and closes it. If sizes is not nil,
+// it holds the expected length of each part's file content.
+func writeParts(w *multipart.Writer, parts []Part, sizes []int64) error {
+	for i, p := range parts {
+		if p.r == nil {
+			err := w.WriteField(p.name, p.value)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		pw, err := p.create(w)
+		if err != nil {
+			return err
+		}
+		if sizes == nil {
+			_, err = io.Copy(pw, p.r)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		n, err := io.CopyN(pw, p.r, sizes[i])
+		if err != nil {
+			if err == io.EOF {
+				err = fmt.Errorf("arrgh: short file %q: wrote %d bytes of %d", p.filename, n, sizes[i])
+			}
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// multipartSize returns the length of the multipart body holding the
+// provided parts and the length of each part's file content. If the size
+// of any file cannot be determined, the returned length is -1 and sizes
+// is nil.
+func multipartSize(boundary string, parts []Part) (length int64, sizes []int64, err error) {
+	var c counter
+	w := multipart.NewWriter(&c)
+	err = w.SetBoundary(boundary)
+	if err != nil {
+		return -1, nil, err
+	}
+	sizes = make([]int64, len(parts))
+	for i, p := range parts {
+		if p.r == nil {
+			err = w.WriteField(p.name, p.value)
+			if err != nil {
+				return -1, nil, err
+			}
+			continue
+		}
+		n := readerSize(p.r)
+		if n < 0 {
+			return -1, nil, nil
+		}
+		sizes[i] = n
+		length += n
+		_, err = p.create(w)
+		if err != nil {
+			return -1, nil, err
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		return -1, nil, err
+	}
+	return length + c.n, sizes, nil
+}
+
+// readerSize returns the number of bytes remaining to be read from r
+// or -1 if this cannot be determined.
+func readerSize(r io.Reader) int64 {
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case interface{ Stat() (os.FileInfo, error) }:
+		fi, err := r.Stat()
+		if err != nil || !fi.Mode().IsRegular() {
+			return -1
+		}
+		size := fi.Size()
+		if s, ok := r.(io.Seeker); ok {
+			off, err := s.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return -1
+			}
+			size -= off
+		}
+		return size
+	}
+	return -1
+}
+
+// counter is an io.Writer that counts the bytes written to it.
+type counter struct {
+	n int64
+}
+
+func (c *counter) Write(b []byte) (int, error) {
+	c.n += int64(len(b))
+	return len(b), nil
+}
@@ -0,0 +1,249 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var streamMultipartTests = []struct {
+	params Params
+	files  func() Files
+
+	wantFiles map[string]string
+	wantSized bool
+}{
+	{
+		params: Params{"header": "bar", "baz": "qux"},
+		files: func() Files {
+			return Files{"boop": namedReader{name: "boop", ReadSeeker: strings.NewReader("Lorem ipsum dolor sit amet.")}}
+		},
+		wantFiles: map[string]string{"boop": "Lorem ipsum dolor sit amet."},
+		wantSized: false,
+	},
+	{
+		params: Params{"header": "FALSE"},
+		files: func() Files {
+			f, err := os.Open("mydata.csv")
+			if err != nil {
+				panic("failed to to open test file")
+			}
+			return Files{"mydata.csv": f, "other.txt": lenNamedReader{name: "other.txt", Reader: strings.NewReader("text")}}
+		},
+		wantFiles: map[string]string{"mydata.csv": mustRead("mydata.csv"), "other.txt": "text"},
+		wantSized: true,
+	},
+}
+
+func TestStreamMultipart(t *testing.T) {
+	for _, test := range streamMultipartTests {
+		content, body, err := StreamMultipart(test.params, test.files())
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			t.Errorf("unexpected error reading body: %v", err)
+			continue
+		}
+		if sized := body.Size() >= 0; sized != test.wantSized {
+			t.Errorf("unexpected sized status: got:%t want:%t", sized, test.wantSized)
+		}
+		if test.wantSized && body.Size() != int64(len(b)) {
+			t.Errorf("unexpected size: got:%d want:%d", body.Size(), len(b))
+		}
+
+		_, params, err := mime.ParseMediaType(content)
+		if err != nil {
+			t.Errorf("failed to parse MIME type: %v", err)
+			continue
+		}
+		mr := multipart.NewReader(bytes.NewReader(b), params["boundary"])
+		gotParams := make(Params)
+		gotFiles := make(map[string]string)
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("failed to parse MIME part: %v", err)
+			}
+			b, err := ioutil.ReadAll(p)
+			if err != nil {
+				t.Fatalf("failed to read MIME part: %v", err)
+			}
+			if name := p.FileName(); name != "" {
+				gotFiles[name] = string(b)
+			} else {
+				gotParams[p.FormName()] = string(b)
+			}
+		}
+		if !reflect.DeepEqual(gotParams, test.params) {
+			t.Errorf("unexpected parameters: got:%v want:%v", gotParams, test.params)
+		}
+		if !reflect.DeepEqual(gotFiles, test.wantFiles) {
+			t.Errorf("unexpected files: got:%v want:%v", gotFiles, test.wantFiles)
+		}
+	}
+}
+
+func TestStreamMultipartError(t *testing.T) {
+	errBroken := errors.New("broken")
+	_, body, err := StreamMultipart(nil, Files{"f": namedReader{name: "f", ReadSeeker: errReader{errBroken}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = ioutil.ReadAll(body)
+	if err != errBroken {
+		t.Errorf("unexpected error: got:%v want:%v", err, errBroken)
+	}
+}
+
+func TestPostContentLength(t *testing.T) {
+	var gotLength int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotLength = r.ContentLength
+		io.Copy(ioutil.Discard, r.Body)
+	}))
+	defer srv.Close()
+	s := testSession(t, srv.URL)
+
+	content, body, err := StreamMultipart(Params{"x": "1"}, Files{"f": lenNamedReader{name: "f", Reader: strings.NewReader("data")}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := s.Post("library/base/R/identity", content, nil, body)
+	if err != nil {
+		t.Fatalf("unexpected error for POST: %v", err)
+	}
+	resp.Body.Close()
+	if gotLength != body.Size() {
+		t.Errorf("unexpected content length: got:%d want:%d", gotLength, body.Size())
+	}
+}
+
+// testSession returns a session for the server at host without
+// waiting for the server to respond.
+func testSession(t *testing.T, host string) *Session {
+	t.Helper()
+	u, err := url.Parse(host)
+	if err != nil {
+		t.Fatalf("failed to parse host: %v", err)
+	}
+	u.Path = "/ocpu"
+	return &Session{host: u, root: "/ocpu"}
+}
+
+type lenNamedReader struct {
+	name string
+	*strings.Reader
+}
+
+func (r lenNamedReader) Name() string { return r.name }
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error)       { return 0, r.err }
+func (r errReader) Seek(int64, int) (int64, error) { return 0, r.err }
+
+func mustRead(path string) string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+func TestMultipartParts(t *testing.T) {
+	jsonPart, err := JSONField("x", map[string][]int{"a": {1, 2}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f, err := os.DirFS(".").Open("mydata.csv")
+	if err != nil {
+		t.Fatalf("failed to open test file: %v", err)
+	}
+	defer f.Close()
+	fsPart, err := FSFile("file", f, "text/csv")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parts := []Part{
+		Field("header", "FALSE"),
+		fsPart,
+		jsonPart,
+		BytesFile("bytes", "b.bin", []byte{0, 1, 2}, ""),
+		StringFile("string", "s.txt", "text", "text/plain"),
+		StringFile("unnamed", "", "unnamed text", ""),
+	}
+	type want struct {
+		name, filename, typ, content string
+	}
+	wants := []want{
+		{name: "header", content: "FALSE"},
+		{name: "file", filename: "mydata.csv", typ: "text/csv", content: mustRead("mydata.csv")},
+		{name: "x", content: `{"a":[1,2]}`},
+		{name: "bytes", filename: "b.bin", typ: "application/octet-stream", content: "\x00\x01\x02"},
+		{name: "string", filename: "s.txt", typ: "text/plain", content: "text"},
+		{name: "unnamed", typ: "application/octet-stream", content: "unnamed text"},
+	}
+
+	content, body, err := StreamMultipartParts(parts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatalf("unexpected error reading body: %v", err)
+	}
+	if body.Size() != int64(len(b)) {
+		t.Errorf("unexpected size: got:%d want:%d", body.Size(), len(b))
+	}
+
+	_, params, err := mime.ParseMediaType(content)
+	if err != nil {
+		t.Fatalf("failed to parse MIME type: %v", err)
+	}
+	mr := multipart.NewReader(bytes.NewReader(b), params["boundary"])
+	var got []want
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to parse MIME part: %v", err)
+		}
+		b, err := ioutil.ReadAll(p)
+		if err != nil {
+			t.Fatalf("failed to read MIME part: %v", err)
+		}
+		got = append(got, want{
+			name:     p.FormName(),
+			filename: p.FileName(),
+			typ:      p.Header.Get("Content-Type"),
+			content:  string(b),
+		})
+	}
+	if !reflect.DeepEqual(got, wants) {
+		t.Errorf("unexpected parts:\ngot: %q\nwant:%q", got, wants)
+	}
+}
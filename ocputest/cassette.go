@@ -0,0 +1,324 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ocputest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Mode is the operating mode of a Recorder.
+type Mode int
+
+const (
+	// Record sends requests to the server and records the
+	// interactions to the cassette.
+	Record Mode = iota
+
+	// Replay serves responses from the interactions held
+	// in the cassette without contacting a server.
+	Replay
+)
+
+// Cassette is a recorded sequence of HTTP interactions.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded HTTP request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a recorded HTTP request.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitempty"`
+}
+
+// RecordedResponse is a recorded HTTP response.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       Body        `json:"body,omitempty"`
+}
+
+// Body is a recorded message body. It is encoded as a JSON string
+// if it is valid UTF-8 and as an object holding the base64 encoding
+// of the body otherwise.
+type Body []byte
+
+// MarshalJSON implements the json.Marshaler interface.
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(struct {
+		Base64 string `json:"base64"`
+	}{Base64: base64.StdEncoding.EncodeToString(b)})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err == nil {
+		*b = Body(s)
+		return nil
+	}
+	var enc struct {
+		Base64 string `json:"base64"`
+	}
+	err = json.Unmarshal(data, &enc)
+	if err != nil {
+		return err
+	}
+	*b, err = base64.StdEncoding.DecodeString(enc.Base64)
+	return err
+}
+
+// Recorder is an http.RoundTripper that records HTTP interactions to a
+// cassette file or replays them from it.
+//
+// OpenCPU session keys in recorded requests and responses are replaced
+// with stable keys numbered in order of their first appearance, so a
+// cassette recorded against a real server replays deterministically.
+// Multipart boundaries are similarly normalised and the Date header is
+// not recorded. Requests are matched during replay by method, URL path
+// and query, and normalised body; interactions are replayed in their
+// recorded order when more than one request matches.
+type Recorder struct {
+	mode      Mode
+	path      string
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+	keys     map[string]string
+}
+
+// NewRecorder returns a Recorder using the cassette file at path in the
+// given mode. In Record mode, requests are sent using transport, or
+// http.DefaultTransport if transport is nil, and the cassette is written
+// when Stop is called. In Replay mode, the cassette is read from path.
+func NewRecorder(path string, mode Mode, transport http.RoundTripper) (*Recorder, error) {
+	r := &Recorder{mode: mode, path: path, transport: transport, keys: make(map[string]string)}
+	switch mode {
+	case Record:
+		if r.transport == nil {
+			r.transport = http.DefaultTransport
+		}
+	case Replay:
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(b, &r.cassette)
+		if err != nil {
+			return nil, fmt.Errorf("ocputest: invalid cassette %s: %w", path, err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	default:
+		return nil, fmt.Errorf("ocputest: invalid recorder mode: %d", mode)
+	}
+	return r, nil
+}
+
+// Client returns an HTTP client using the recorder as its transport.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Stop writes the recorded interactions to the cassette file in Record
+// mode. It is a no-op in Replay mode.
+func (r *Recorder) Stop() error {
+	if r.mode != Record {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, err := json.MarshalIndent(r.cassette, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, append(b, '\n'), 0o644)
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.mode == Replay {
+		return r.replay(req, body)
+	}
+	return r.record(req, body)
+}
+
+// record sends the request and records the interaction.
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.Body = ioutil.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+	resp, err := r.transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: r.normaliseRequest(req, body),
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     r.normaliseHeader(resp.Header),
+			Body:       r.normalise(respBody),
+		},
+	})
+	return resp, nil
+}
+
+// replay returns the response for the first unused interaction
+// matching the request.
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	want := r.normaliseRequest(req, body)
+	for i, in := range r.cassette.Interactions {
+		if r.used[i] || !matches(in.Request, want) {
+			continue
+		}
+		r.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        in.Response.Header.Clone(),
+			Body:          ioutil.NopCloser(bytes.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("ocputest: no recorded interaction for %s %s", want.Method, want.URL)
+}
+
+// matches returns whether the recorded request matches the normalised
+// request want.
+func matches(rec, want RecordedRequest) bool {
+	return rec.Method == want.Method && rec.URL == want.URL && bytes.Equal(rec.Body, want.Body)
+}
+
+// boundary is the multipart boundary used in recorded requests.
+const boundary = "ocputest-boundary"
+
+// normaliseRequest returns the recorded form of req with the given body.
+// The URL is recorded without its scheme and host so that cassettes may
+// be replayed against any server.
+func (r *Recorder) normaliseRequest(req *http.Request, body []byte) RecordedRequest {
+	u := *req.URL
+	u.Scheme = ""
+	u.Host = ""
+	u.User = nil
+	header := req.Header.Clone()
+	if typ, params, err := mime.ParseMediaType(header.Get("Content-Type")); err == nil && strings.HasPrefix(typ, "multipart/") {
+		if b := params["boundary"]; b != "" {
+			body = bytes.ReplaceAll(body, []byte(b), []byte(boundary))
+			params["boundary"] = boundary
+			header.Set("Content-Type", mime.FormatMediaType(typ, params))
+		}
+	}
+	return RecordedRequest{
+		Method: req.Method,
+		URL:    string(r.normalise([]byte(u.String()))),
+		Header: r.normaliseHeader(header),
+		Body:   r.normalise(body),
+	}
+}
+
+// normaliseHeader returns a copy of h with session keys normalised
+// and the Date header removed.
+func (r *Recorder) normaliseHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	n := make(http.Header, len(h))
+	for k, v := range h {
+		if k == "Date" {
+			continue
+		}
+		for _, e := range v {
+			n[k] = append(n[k], string(r.normalise([]byte(e))))
+		}
+	}
+	return n
+}
+
+// sessionKey matches OpenCPU session keys as the package example
+// tests' mask function does.
+var sessionKey = regexp.MustCompile(`x[0-9a-f]{10,}`)
+
+// normalise returns b with session keys replaced by their normalised
+// forms. Bodies that are not valid UTF-8 are returned unaltered.
+func (r *Recorder) normalise(b []byte) []byte {
+	if len(b) == 0 || !utf8.Valid(b) {
+		return b
+	}
+	return sessionKey.ReplaceAllFunc(b, func(k []byte) []byte {
+		if r.mode == Replay {
+			// Keys seen during replay were issued by the
+			// cassette and so are already normalised.
+			return k
+		}
+		n, ok := r.keys[string(k)]
+		if !ok {
+			n = fmt.Sprintf("x%010x", len(r.keys)+1)
+			r.keys[string(k)] = n
+		}
+		return []byte(n)
+	})
+}
+
+// RecorderMode returns Record if the environment variable named by env
+// is non-empty or the cassette file at path does not exist, and Replay
+// otherwise. It allows a test to record its cassette on first run, and
+// re-record it on request.
+func RecorderMode(path, env string) Mode {
+	if os.Getenv(env) != "" {
+		return Record
+	}
+	_, err := os.Stat(path)
+	if err != nil {
+		return Record
+	}
+	return Replay
+}
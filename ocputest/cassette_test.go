@@ -0,0 +1,105 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ocputest_test
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kortschak/arrgh"
+	"github.com/kortschak/arrgh/ocputest"
+)
+
+func TestRecorder(t *testing.T) {
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+
+	srv := ocputest.NewServer()
+	rec, err := ocputest.NewRecorder(cassette, ocputest.Record, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating recorder: %v", err)
+	}
+	recorded, recordedKeys := recorderWorkload(t, srv.URL, rec)
+	srv.Close()
+	err = rec.Stop()
+	if err != nil {
+		t.Fatalf("unexpected error writing cassette: %v", err)
+	}
+
+	b, err := ioutil.ReadFile(cassette)
+	if err != nil {
+		t.Fatalf("failed to read cassette: %v", err)
+	}
+	for _, k := range recordedKeys {
+		if strings.Contains(string(b), k) {
+			t.Errorf("cassette contains unnormalised session key %s", k)
+		}
+	}
+
+	// Replay with the server closed.
+	rec, err = ocputest.NewRecorder(cassette, ocputest.Replay, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating replayer: %v", err)
+	}
+	replayed, replayedKeys := recorderWorkload(t, srv.URL, rec)
+	if !reflect.DeepEqual(replayed, recorded) {
+		t.Errorf("unexpected replayed results:\ngot: %q\nwant:%q", replayed, recorded)
+	}
+	if want := []string{"x0000000001", "x0000000002", "x0000000003"}; !reflect.DeepEqual(replayedKeys, want) {
+		t.Errorf("unexpected replayed keys: got:%q want:%q", replayedKeys, want)
+	}
+
+	// Unrecorded requests fail.
+	_, err = arrgh.NewRemoteSessionWithClient(srv.URL, "", time.Nanosecond, rec.Client())
+	if err == nil {
+		t.Errorf("expected error for unrecorded request")
+	}
+}
+
+// recorderWorkload performs a sequence of calls using the recorder and
+// returns the values obtained and the session keys issued.
+func recorderWorkload(t *testing.T, host string, rec *ocputest.Recorder) (values, keys []string) {
+	t.Helper()
+	s, err := arrgh.NewRemoteSessionWithClient(host, "", 10*time.Second, rec.Client())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	ctx := context.Background()
+
+	id, err := s.Call(ctx, "library/base/R/identity", "application/json", nil, strings.NewReader(`{"x":[1,2,3]}`))
+	if err != nil {
+		t.Fatalf("unexpected error calling identity: %v", err)
+	}
+	sum, err := s.Call(ctx, "library/base/R/sum", "application/x-www-form-urlencoded", nil, strings.NewReader("x="+id.Key))
+	if err != nil {
+		t.Fatalf("unexpected error calling sum: %v", err)
+	}
+	content, body, err := arrgh.MultipartParts(arrgh.Field("header", "TRUE"), arrgh.StringFile("file", "d.csv", "a,b\n1,2\n", "text/csv"))
+	if err != nil {
+		t.Fatalf("unexpected error building multipart body: %v", err)
+	}
+	csv, err := s.Call(ctx, "library/utils/R/read.csv", content, nil, body)
+	if err != nil {
+		t.Fatalf("unexpected error calling read.csv: %v", err)
+	}
+	for _, r := range []*arrgh.Result{id, sum, csv} {
+		keys = append(keys, r.Key)
+		rc, err := s.Object(ctx, "tmp/"+r.Key+"/R/.val", arrgh.JSON, nil)
+		if err != nil {
+			t.Fatalf("unexpected error getting value: %v", err)
+		}
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("unexpected error reading value: %v", err)
+		}
+		values = append(values, string(b))
+	}
+	return values, keys
+}
@@ -0,0 +1,538 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package conformance provides a test suite checking that an OpenCPU
+// server behaves as the arrgh package expects.
+//
+// The suite is run from a test using a session connected to the
+// server to be checked:
+//
+//	func TestServer(t *testing.T) {
+//		s, err := arrgh.NewRemoteSession(host, "", 10*time.Second)
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		r := conformance.Test(t, s)
+//		t.Log(r)
+//	}
+//
+// The suite uses only functions from the base and utils packages.
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kortschak/arrgh"
+)
+
+// Report summarises the results of running the conformance suite
+// against a server.
+type Report struct {
+	// Version is the OpenCPU version of the server.
+	Version string
+
+	// Capabilities is the set of capabilities the
+	// server was observed to support when probed.
+	Capabilities arrgh.Capability
+
+	// Claimed is the set of capabilities reported
+	// for the server by the session's Capabilities
+	// method.
+	Claimed arrgh.Capability
+
+	// Passed, Failed and Skipped hold the names of
+	// the checks with each outcome. Checks are
+	// skipped when the server was not observed to
+	// have the capability they require.
+	Passed, Failed, Skipped []string
+}
+
+func (r *Report) String() string {
+	caps := r.Capabilities.String()
+	if r.Claimed != r.Capabilities {
+		caps += fmt.Sprintf(", claimed: %v", r.Claimed)
+	}
+	return fmt.Sprintf("OpenCPU %s (capabilities: %s): %d passed, %d failed, %d skipped",
+		r.Version, caps, len(r.Passed), len(r.Failed), len(r.Skipped))
+}
+
+// check is a single conformance check.
+type check struct {
+	name string
+	cap  arrgh.Capability
+	fn   func(ctx context.Context, t *testing.T, s *arrgh.Session)
+}
+
+var checks = []check{
+	{name: "Info", fn: testInfo},
+	{name: "CallJSON", fn: testCallJSON},
+	{name: "CallURLEncoded", fn: testCallURLEncoded},
+	{name: "CallMultipart", fn: testCallMultipart},
+	{name: "Invoke", fn: testInvoke},
+	{name: "KeyChaining", fn: testKeyChaining},
+	{name: "SessionObjects", fn: testSessionObjects},
+	{name: "Formats", fn: testFormats},
+	{name: "NDJSON", cap: arrgh.CapNDJSON, fn: testNDJSON},
+	{name: "Upload", fn: testUpload},
+	{name: "Errors", fn: testErrors},
+	{name: "Warnings", cap: arrgh.CapWarnings, fn: testWarnings},
+	{name: "Apps", cap: arrgh.CapApps, fn: testApps},
+}
+
+// probes lists each capability and a function reporting whether the
+// server was observed to have it. The probes make requests directly,
+// so they do not depend on the capabilities claimed for the server.
+var probes = []struct {
+	cap   arrgh.Capability
+	probe func(ctx context.Context, s *arrgh.Session) (bool, error)
+}{
+	{cap: arrgh.CapWarnings, probe: probeConditions},
+	{cap: arrgh.CapApps, probe: func(ctx context.Context, s *arrgh.Session) (bool, error) {
+		return found(ctx, s, "apps/")
+	}},
+	{cap: arrgh.CapNDJSON, probe: probeFormat(arrgh.NDJSON)},
+	{cap: arrgh.CapFeather, probe: probeFormat(arrgh.Feather)},
+	{cap: arrgh.CapParquet, probe: probeFormat(arrgh.Parquet)},
+}
+
+// probe returns the capabilities the server was observed to have.
+func probe(ctx context.Context, s *arrgh.Session) (arrgh.Capability, error) {
+	var caps arrgh.Capability
+	for _, p := range probes {
+		ok, err := p.probe(ctx, s)
+		if err != nil {
+			return 0, fmt.Errorf("probing %v: %w", p.cap, err)
+		}
+		if ok {
+			caps |= p.cap
+		}
+	}
+	return caps, nil
+}
+
+// probeConditions returns whether the server records the warnings and
+// messages of a call.
+func probeConditions(ctx context.Context, s *arrgh.Session) (bool, error) {
+	for _, kind := range []string{"warning", "message"} {
+		res, err := s.Call(ctx, "library/base/R/"+kind, "application/json", nil, strings.NewReader(`{"x":"conformance probe"}`))
+		if err != nil {
+			return false, err
+		}
+		ok, err := found(ctx, s, "tmp/"+res.Key+"/"+kind+"s")
+		if !ok || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// probeFormat returns a probe reporting whether the server can encode
+// the cars dataset, which is always installed with R, in format f.
+func probeFormat(f arrgh.Format) func(ctx context.Context, s *arrgh.Session) (bool, error) {
+	return func(ctx context.Context, s *arrgh.Session) (bool, error) {
+		return found(ctx, s, "library/datasets/data/cars/"+string(f))
+	}
+}
+
+// found returns whether a GET of the path succeeds.
+func found(ctx context.Context, s *arrgh.Session, path string) (bool, error) {
+	resp, err := s.GetContext(ctx, path, nil)
+	if err != nil {
+		return false, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	return 200 <= resp.StatusCode && resp.StatusCode < 300, nil
+}
+
+// Test runs the conformance suite against the server connected to s,
+// running each check as a subtest of t, and returns a report of the
+// outcomes and the server's capabilities. The capabilities are probed
+// by making requests that use them, and checks that need a capability
+// the server was not observed to have are skipped.
+func Test(t *testing.T, s *arrgh.Session) *Report {
+	t.Helper()
+	ctx := context.Background()
+
+	var r Report
+	var err error
+	r.Version, err = s.Version(ctx)
+	if err != nil {
+		t.Fatalf("failed to get server version: %v", err)
+	}
+	r.Claimed, err = s.Capabilities(ctx)
+	if err != nil {
+		t.Fatalf("failed to get server capabilities: %v", err)
+	}
+	r.Capabilities, err = probe(ctx, s)
+	if err != nil {
+		t.Fatalf("failed to probe server capabilities: %v", err)
+	}
+
+	for _, c := range checks {
+		c := c
+		var skipped bool
+		ok := t.Run(c.name, func(t *testing.T) {
+			defer func() { skipped = t.Skipped() }()
+			if c.cap != 0 && r.Capabilities&c.cap == 0 {
+				t.Skipf("server does not support %v", c.cap)
+			}
+			c.fn(ctx, t, s)
+		})
+		switch {
+		case skipped:
+			r.Skipped = append(r.Skipped, c.name)
+		case ok:
+			r.Passed = append(r.Passed, c.name)
+		default:
+			r.Failed = append(r.Failed, c.name)
+		}
+	}
+	return &r
+}
+
+func testInfo(ctx context.Context, t *testing.T, s *arrgh.Session) {
+	info, err := s.Info(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error getting info: %v", err)
+	}
+	if info.RVersion == "" {
+		t.Errorf("no R version in info:\n%s", info.Text)
+	}
+	if info.Platform == "" {
+		t.Errorf("no platform in info:\n%s", info.Text)
+	}
+}
+
+func testCallJSON(ctx context.Context, t *testing.T, s *arrgh.Session) {
+	res, err := s.Call(ctx, "library/base/R/sum", "application/json", nil, strings.NewReader(`{"x":[1,2,3]}`))
+	if err != nil {
+		t.Fatalf("unexpected error calling sum: %v", err)
+	}
+	checkSum(ctx, t, s, res, 6)
+}
+
+func testCallURLEncoded(ctx context.Context, t *testing.T, s *arrgh.Session) {
+	res, err := s.Call(ctx, "library/base/R/sum", "application/x-www-form-urlencoded", nil, strings.NewReader("x="+url.QueryEscape("[1,2,3]")))
+	if err != nil {
+		t.Fatalf("unexpected error calling sum: %v", err)
+	}
+	checkSum(ctx, t, s, res, 6)
+}
+
+func testCallMultipart(ctx context.Context, t *testing.T, s *arrgh.Session) {
+	content, body, err := arrgh.MultipartParts(arrgh.Field("x", "[1,2,3]"))
+	if err != nil {
+		t.Fatalf("unexpected error building multipart body: %v", err)
+	}
+	res, err := s.Call(ctx, "library/base/R/sum", content, nil, body)
+	if err != nil {
+		t.Fatalf("unexpected error calling sum: %v", err)
+	}
+	checkSum(ctx, t, s, res, 6)
+}
+
+func testInvoke(ctx context.Context, t *testing.T, s *arrgh.Session) {
+	sum := arrgh.Function{Package: arrgh.Package{Library: arrgh.SystemLibrary, Name: "base"}, Name: "sum"}
+	v, err := s.Invoke(ctx, sum, map[string]interface{}{"x": []int{1, 2, 3}}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error invoking sum: %v", err)
+	}
+	var got []float64
+	err = v.Decode(&got)
+	if err != nil {
+		t.Fatalf("unexpected error decoding %s: %v", v, err)
+	}
+	if want := []float64{6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected sum: got:%v want:%v", got, want)
+	}
+}
+
+func testKeyChaining(ctx context.Context, t *testing.T, s *arrgh.Session) {
+	x, err := s.Call(ctx, "library/base/R/identity", "application/json", nil, strings.NewReader(`{"x":[1,2,3,4]}`))
+	if err != nil {
+		t.Fatalf("unexpected error calling identity: %v", err)
+	}
+	if x.Key == "" {
+		t.Fatalf("no session key returned")
+	}
+	res, err := s.Call(ctx, "library/base/R/sum", "application/x-www-form-urlencoded", nil, strings.NewReader("x="+x.Key))
+	if err != nil {
+		t.Fatalf("unexpected error calling sum with session key: %v", err)
+	}
+	if res.Key == x.Key {
+		t.Errorf("session key reused: %s", res.Key)
+	}
+	checkSum(ctx, t, s, res, 10)
+}
+
+func testSessionObjects(ctx context.Context, t *testing.T, s *arrgh.Session) {
+	res, err := s.Call(ctx, "library/base/R/sum", "application/json", nil, strings.NewReader(`{"x":[1,2,3]}`))
+	if err != nil {
+		t.Fatalf("unexpected error calling sum: %v", err)
+	}
+	for _, obj := range []string{"source", "console", "stdout", "info"} {
+		found := false
+		for _, p := range res.Paths {
+			if p == "tmp/"+res.Key+"/"+obj {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("%s not listed in session paths: %q", obj, res.Paths)
+		}
+		b := get(ctx, t, s, "tmp/"+res.Key+"/"+obj, "")
+		if len(b) == 0 {
+			t.Errorf("empty %s", obj)
+		}
+	}
+	if b := get(ctx, t, s, "tmp/"+res.Key+"/console", ""); !bytes.Contains(b, []byte("6")) {
+		t.Errorf("console does not include printed result:\n%s", b)
+	}
+	_, err = s.SessionInfo(ctx, res.Key)
+	if err != nil {
+		t.Errorf("unexpected error getting session info: %v", err)
+	}
+}
+
+// frame is a data frame used to check object formats.
+const frame = `[{"a":1,"b":"x"},{"a":2,"b":"y"}]`
+
+func testFormats(ctx context.Context, t *testing.T, s *arrgh.Session) {
+	res, err := s.Call(ctx, "library/base/R/identity", "application/json", nil, strings.NewReader(`{"x":`+frame+`}`))
+	if err != nil {
+		t.Fatalf("unexpected error calling identity: %v", err)
+	}
+	val := "tmp/" + res.Key + "/R/.val"
+
+	var got []map[string]interface{}
+	err = json.Unmarshal(get(ctx, t, s, val, arrgh.JSON), &got)
+	if err != nil {
+		t.Errorf("unexpected error decoding json: %v", err)
+	}
+	var want []map[string]interface{}
+	json.Unmarshal([]byte(frame), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected json value: got:%v want:%v", got, want)
+	}
+
+	for _, f := range []struct {
+		format arrgh.Format
+		sep    string
+	}{
+		{format: arrgh.CSV, sep: ","},
+		{format: arrgh.TSV, sep: "\t"},
+	} {
+		b := get(ctx, t, s, val, f.format)
+		lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(string(b), `"`, "")), "\n")
+		if len(lines) != 3 || strings.TrimSpace(lines[0]) != "a"+f.sep+"b" {
+			t.Errorf("unexpected %s value:\n%s", f.format, b)
+		}
+	}
+
+	for _, f := range []arrgh.Format{arrgh.Text, arrgh.Print} {
+		b := get(ctx, t, s, val, f)
+		if !bytes.Contains(b, []byte("a")) || !bytes.Contains(b, []byte("y")) {
+			t.Errorf("unexpected %s value:\n%s", f, b)
+		}
+	}
+}
+
+func testNDJSON(ctx context.Context, t *testing.T, s *arrgh.Session) {
+	res, err := s.Call(ctx, "library/base/R/identity", "application/json", nil, strings.NewReader(`{"x":`+frame+`}`))
+	if err != nil {
+		t.Fatalf("unexpected error calling identity: %v", err)
+	}
+	b := get(ctx, t, s, "tmp/"+res.Key+"/R/.val", arrgh.NDJSON)
+	dec := json.NewDecoder(bytes.NewReader(b))
+	var n int
+	for dec.More() {
+		var row map[string]interface{}
+		err = dec.Decode(&row)
+		if err != nil {
+			t.Fatalf("unexpected error decoding ndjson: %v", err)
+		}
+		n++
+	}
+	if n != 2 {
+		t.Errorf("unexpected number of ndjson rows: got:%d want:2", n)
+	}
+}
+
+func testUpload(ctx context.Context, t *testing.T, s *arrgh.Session) {
+	const name = "conformance.csv"
+	content, body, err := arrgh.MultipartParts(
+		arrgh.StringFile("file", name, "a,b\n1,x\n2,y\n", "text/csv"),
+		arrgh.Field("header", "TRUE"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error building multipart body: %v", err)
+	}
+	res, err := s.Call(ctx, "library/utils/R/read.csv", content, nil, body)
+	if err != nil {
+		t.Fatalf("unexpected error calling read.csv: %v", err)
+	}
+	files, err := s.Files(ctx, res.Key)
+	if err != nil {
+		t.Fatalf("unexpected error listing files: %v", err)
+	}
+	found := false
+	for _, f := range files {
+		if f == name {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Errorf("uploaded file not in session files: %q", files)
+	}
+	r, err := s.OpenFile(ctx, res.Key, name)
+	if err != nil {
+		t.Fatalf("unexpected error opening uploaded file: %v", err)
+	}
+	b, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("unexpected error reading uploaded file: %v", err)
+	}
+	if string(b) != "a,b\n1,x\n2,y\n" {
+		t.Errorf("unexpected uploaded file content: %q", b)
+	}
+	var rows []map[string]interface{}
+	err = json.Unmarshal(get(ctx, t, s, "tmp/"+res.Key+"/R/.val", arrgh.JSON), &rows)
+	if err != nil {
+		t.Fatalf("unexpected error decoding read.csv result: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Errorf("unexpected number of rows: got:%d want:2", len(rows))
+	}
+}
+
+func testErrors(ctx context.Context, t *testing.T, s *arrgh.Session) {
+	const msg = "conformance failure"
+	_, err := s.Call(ctx, "library/base/R/stop", "application/json", nil, strings.NewReader(`{"x":"`+msg+`"}`))
+	var e *arrgh.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("unexpected error type for stop: %T: %v", err, err)
+	}
+	if e.StatusCode != 400 {
+		t.Errorf("unexpected status for stop: got:%d want:400", e.StatusCode)
+	}
+	if !strings.Contains(e.Message, msg) {
+		t.Errorf("error message does not include R error: %q", e.Message)
+	}
+
+	_, err = s.Files(ctx, "x0000000000")
+	if !errors.As(err, &e) {
+		t.Fatalf("unexpected error type for missing session: %T: %v", err, err)
+	}
+	if e.StatusCode < 400 || e.StatusCode >= 500 {
+		t.Errorf("unexpected status for missing session: got:%d want:4xx", e.StatusCode)
+	}
+}
+
+func testWarnings(ctx context.Context, t *testing.T, s *arrgh.Session) {
+	const msg = "conformance warning"
+	res, err := s.Call(ctx, "library/base/R/warning", "application/json", nil, strings.NewReader(`{"x":"`+msg+`"}`))
+	if err != nil {
+		t.Fatalf("unexpected error calling warning: %v", err)
+	}
+	warnings, err := s.Warnings(ctx, res.Key)
+	if err != nil {
+		t.Fatalf("unexpected error getting warnings: %v", err)
+	}
+	if !containsLine(warnings, msg) {
+		t.Errorf("warning not reported: %q", warnings)
+	}
+
+	const note = "conformance message"
+	res, err = s.Call(ctx, "library/base/R/message", "application/json", nil, strings.NewReader(`{"x":"`+note+`"}`))
+	if err != nil {
+		t.Fatalf("unexpected error calling message: %v", err)
+	}
+	messages, err := s.Messages(ctx, res.Key)
+	if err != nil {
+		t.Fatalf("unexpected error getting messages: %v", err)
+	}
+	if !containsLine(messages, note) {
+		t.Errorf("message not reported: %q", messages)
+	}
+}
+
+func testApps(ctx context.Context, t *testing.T, s *arrgh.Session) {
+	_, err := s.Apps(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error listing apps: %v", err)
+	}
+}
+
+// checkSum checks that the value of the result is the single number want.
+func checkSum(ctx context.Context, t *testing.T, s *arrgh.Session, res *arrgh.Result, want float64) {
+	t.Helper()
+	if res.Key == "" {
+		t.Fatalf("no session key returned")
+	}
+	var got []float64
+	err := json.Unmarshal(get(ctx, t, s, "tmp/"+res.Key+"/R/.val", arrgh.JSON), &got)
+	if err != nil {
+		t.Fatalf("unexpected error decoding sum: %v", err)
+	}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("unexpected sum: got:%v want:[%v]", got, want)
+	}
+}
+
+// get returns the content at the given path, encoded in format if
+// it is not empty.
+func get(ctx context.Context, t *testing.T, s *arrgh.Session, path string, format arrgh.Format) []byte {
+	t.Helper()
+	var (
+		r   io.ReadCloser
+		err error
+	)
+	if format == "" {
+		var resp *http.Response
+		resp, err = s.GetContext(ctx, path, nil)
+		if err == nil {
+			r = resp.Body
+			if resp.StatusCode >= 400 {
+				r.Close()
+				err = errors.New(resp.Status)
+			}
+		}
+	} else {
+		r, err = s.Object(ctx, path, format, nil)
+	}
+	if err != nil {
+		t.Fatalf("unexpected error getting %s %s: %v", path, format, err)
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error reading %s %s: %v", path, format, err)
+	}
+	return b
+}
+
+// containsLine returns whether any of lines contains s.
+func containsLine(lines []string, s string) bool {
+	for _, l := range lines {
+		if strings.Contains(l, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conformance_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kortschak/arrgh"
+	"github.com/kortschak/arrgh/ocputest"
+	"github.com/kortschak/arrgh/ocputest/conformance"
+)
+
+func TestOCPUTest(t *testing.T) {
+	srv := ocputest.NewServer()
+	defer srv.Close()
+	s, err := arrgh.NewRemoteSession(srv.URL, "", 10*time.Second)
+	if err != nil {
+		t.Fatalf("failed to connect to test server: %v", err)
+	}
+	r := conformance.Test(t, s)
+	t.Log(r)
+	if len(r.Failed) != 0 || len(r.Skipped) != 0 {
+		t.Errorf("unexpected outcomes: failed:%q skipped:%q", r.Failed, r.Skipped)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	srv := ocputest.NewServer()
+	defer srv.Close()
+	target, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("failed to parse server URL: %v", err)
+	}
+	// The proxied server claims to record conditions
+	// by its version, but does not.
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/warnings") || strings.HasSuffix(r.URL.Path, "/messages") {
+			http.NotFound(w, r)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	defer limited.Close()
+
+	s, err := arrgh.NewRemoteSession(limited.URL, "", 10*time.Second)
+	if err != nil {
+		t.Fatalf("failed to connect to test server: %v", err)
+	}
+	r := conformance.Test(t, s)
+	if r.Capabilities&arrgh.CapWarnings != 0 {
+		t.Errorf("unexpected observed capability: %v", r.Capabilities)
+	}
+	if r.Claimed&arrgh.CapWarnings == 0 {
+		t.Errorf("expected claimed capability: %v", r.Claimed)
+	}
+	if !reflect.DeepEqual(r.Skipped, []string{"Warnings"}) {
+		t.Errorf("unexpected skipped checks: got:%q want:[\"Warnings\"]", r.Skipped)
+	}
+	if want := "(capabilities: apps|ndjson, claimed: warnings|apps|ndjson)"; !strings.Contains(r.String(), want) {
+		t.Errorf("unexpected report: got:%q want to contain:%q", r, want)
+	}
+}
+
+// TestRemote runs the conformance suite against the OpenCPU server
+// specified by the ARRGH_CONFORMANCE_HOST environment variable.
+func TestRemote(t *testing.T) {
+	host := os.Getenv("ARRGH_CONFORMANCE_HOST")
+	if host == "" {
+		t.Skip("ARRGH_CONFORMANCE_HOST not set")
+	}
+	s, err := arrgh.NewRemoteSession(host, os.Getenv("ARRGH_CONFORMANCE_ROOT"), 10*time.Second)
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", host, err)
+	}
+	t.Log(conformance.Test(t, s))
+}
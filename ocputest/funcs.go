@@ -0,0 +1,292 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ocputest
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// identity implements base::identity.
+func identity(c *Call) (interface{}, error) {
+	x, ok := c.Args["x"]
+	if !ok {
+		return nil, errors.New(`argument "x" is missing, with no default`)
+	}
+	return x, nil
+}
+
+// sum implements base::sum for numeric and logical arguments.
+func sum(c *Call) (interface{}, error) {
+	var total float64
+	var add func(v interface{}) error
+	add = func(v interface{}) error {
+		switch v := v.(type) {
+		case nil:
+		case float64:
+			total += v
+		case bool:
+			if v {
+				total++
+			}
+		case []interface{}:
+			for _, e := range v {
+				err := add(e)
+				if err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("invalid 'type' (%T) of argument", v)
+		}
+		return nil
+	}
+	for k, v := range c.Args {
+		if k == "na.rm" {
+			continue
+		}
+		err := add(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return total, nil
+}
+
+// stop implements base::stop.
+func stop(c *Call) (interface{}, error) {
+	return nil, errors.New(conditionMessage(c))
+}
+
+// warning implements base::warning, returning the message.
+func warning(c *Call) (interface{}, error) {
+	msg := conditionMessage(c)
+	c.Warnings = append(c.Warnings, msg)
+	return msg, nil
+}
+
+// message implements base::message.
+func message(c *Call) (interface{}, error) {
+	c.Messages = append(c.Messages, conditionMessage(c))
+	return nil, nil
+}
+
+// conditionMessage returns the message of a condition raised with
+// the arguments of c. Since arguments are named, they are
+// concatenated in order of their names.
+func conditionMessage(c *Call) string {
+	names := make([]string, 0, len(c.Args))
+	for k := range c.Args {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var msg strings.Builder
+	for _, k := range names {
+		fmt.Fprint(&msg, c.Args[k])
+	}
+	return msg.String()
+}
+
+// readCSV implements utils::read.csv for uploaded files, returning
+// the rows of the data frame.
+func readCSV(c *Call) (interface{}, error) {
+	name, ok := c.Args["file"].(string)
+	if !ok {
+		return nil, errors.New(`argument "file" is missing, with no default`)
+	}
+	b, ok := c.Files[name]
+	if !ok {
+		return nil, fmt.Errorf("cannot open file '%s': No such file or directory", name)
+	}
+	header := true
+	if h, ok := c.Args["header"].(bool); ok {
+		header = h
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("no lines available in input")
+	}
+	var names []string
+	if header {
+		names, records = records[0], records[1:]
+	} else {
+		for i := range records[0] {
+			names = append(names, "V"+strconv.Itoa(i+1))
+		}
+	}
+	rows := make([]interface{}, len(records))
+	for i, rec := range records {
+		row := make(map[string]interface{}, len(rec))
+		for j, f := range rec {
+			if j >= len(names) {
+				break
+			}
+			if v, err := strconv.ParseFloat(strings.TrimSpace(f), 64); err == nil {
+				row[names[j]] = v
+			} else {
+				row[names[j]] = f
+			}
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+// encodeJSON returns the JSON encoding of v in the style of jsonlite,
+// where scalars are encoded as length one vectors unless the auto_unbox
+// parameter is true and NULL is encoded as an empty object.
+func encodeJSON(v interface{}, params url.Values) ([]byte, error) {
+	unbox := params.Get("auto_unbox") == "true" || params.Get("auto_unbox") == "TRUE"
+	if v == nil {
+		v = struct{}{}
+	} else if !unbox {
+		switch reflect.ValueOf(v).Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct, reflect.Ptr:
+		default:
+			v = []interface{}{v}
+		}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// encodeNDJSON returns the newline delimited JSON encoding of v. Slices
+// are encoded one element per line, other values as a single line.
+func encodeNDJSON(v interface{}) ([]byte, error) {
+	rows, ok := v.([]interface{})
+	if !ok {
+		rows = []interface{}{v}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range rows {
+		err := enc.Encode(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeCSV returns the delimited text encoding of v. Values that are
+// slices of objects are encoded as data frames, other slices and scalars
+// are encoded as a single column named x.
+func encodeCSV(v interface{}, comma rune) ([]byte, error) {
+	var (
+		names []string
+		rows  [][]string
+	)
+	switch v := v.(type) {
+	case []interface{}:
+		seen := make(map[string]bool)
+		for _, e := range v {
+			if m, ok := e.(map[string]interface{}); ok {
+				for k := range m {
+					if !seen[k] {
+						seen[k] = true
+						names = append(names, k)
+					}
+				}
+			}
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			names = []string{"x"}
+		}
+		for _, e := range v {
+			m, ok := e.(map[string]interface{})
+			if !ok {
+				rows = append(rows, []string{csvValue(e)})
+				continue
+			}
+			row := make([]string, len(names))
+			for i, n := range names {
+				row[i] = csvValue(m[n])
+			}
+			rows = append(rows, row)
+		}
+	case map[string]interface{}:
+		return nil, errors.New("ocputest: cannot encode list as csv")
+	default:
+		names = []string{"x"}
+		rows = [][]string{{csvValue(v)}}
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = comma
+	w.Write(names)
+	w.WriteAll(rows)
+	return buf.Bytes(), w.Error()
+}
+
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NA"
+	case string:
+		return v
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// printValue returns an approximation of R's printed representation of v.
+func printValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL\n"
+	case []interface{}:
+		if len(v) == 0 {
+			return "list()\n"
+		}
+		elems := make([]string, len(v))
+		for i, e := range v {
+			switch e.(type) {
+			case []interface{}, map[string]interface{}:
+				b, _ := json.Marshal(v)
+				return string(b) + "\n"
+			}
+			elems[i] = printScalar(e)
+		}
+		return "[1] " + strings.Join(elems, " ") + "\n"
+	case map[string]interface{}:
+		b, _ := json.Marshal(v)
+		return string(b) + "\n"
+	}
+	return "[1] " + printScalar(v) + "\n"
+}
+
+func printScalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NA"
+	case string:
+		return strconv.Quote(v)
+	}
+	return csvValue(v)
+}
@@ -0,0 +1,685 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ocputest provides an in-process fake OpenCPU server for testing
+// code that uses OpenCPU without an R installation.
+//
+// The server implements the parts of the OpenCPU API used by the arrgh
+// package: function calls with JSON, URL encoded and multipart arguments,
+// session keys and their stored objects, object output formats, file
+// uploads, and the info and apps endpoints. R functions are implemented by Go
+// functions registered with the server. R expressions are not evaluated;
+// URL encoded and multipart arguments must be JSON values, R logical
+// constants, NULL, session keys or calls of registered functions with
+// such arguments.
+//
+// Interactions with a real OpenCPU server may be recorded to a cassette
+// file and replayed offline using a Recorder.
+package ocputest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version is the OpenCPU version reported by the server.
+const Version = "2.2.9"
+
+// Call holds the arguments of a call to a Func.
+type Call struct {
+	// Args holds the named arguments of the call.
+	// Values are decoded from JSON as described for
+	// encoding/json. Arguments naming a session key
+	// hold the value of the session and uploaded file
+	// arguments hold the name of the uploaded file.
+	Args map[string]interface{}
+
+	// Files holds the contents of the files in the
+	// call's working directory, keyed by name.
+	Files map[string][]byte
+
+	// Stdout receives the printed output of the call.
+	Stdout io.Writer
+
+	// Warnings and Messages hold the warnings and
+	// messages raised by the call. A Func may append
+	// to them.
+	Warnings []string
+	Messages []string
+}
+
+// Func is an R function implemented in Go. The returned value is stored
+// in the call's session and must be encodable by encoding/json. Returned
+// errors are reported to the client as R errors.
+type Func func(c *Call) (interface{}, error)
+
+// Server is a fake OpenCPU server.
+type Server struct {
+	// URL is the base URL of the server, of
+	// the form http://ipaddr:port with no
+	// trailing slash. The OpenCPU API is
+	// served under /ocpu.
+	URL string
+
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	funcs    map[string]map[string]Func
+	sessions map[string]*session
+}
+
+// session is a stored call result.
+type session struct {
+	key     string
+	name    string
+	source  string
+	value   interface{}
+	stdout  string
+	warns   []string
+	msgs    []string
+	files   map[string][]byte
+	created time.Time
+}
+
+// NewServer starts and returns a new fake OpenCPU server. The caller should
+// call Close when finished, to shut it down. The server provides the functions
+// base::identity, base::sum, base::stop, base::warning, base::message and
+// utils::read.csv, and the dataset datasets::cars.
+func NewServer() *Server {
+	s := &Server{
+		funcs:    make(map[string]map[string]Func),
+		sessions: make(map[string]*session),
+	}
+	s.Register("base", "identity", identity)
+	s.Register("base", "sum", sum)
+	s.Register("base", "stop", stop)
+	s.Register("base", "warning", warning)
+	s.Register("base", "message", message)
+	s.Register("utils", "read.csv", readCSV)
+	s.srv = httptest.NewServer(http.StripPrefix("/ocpu", http.HandlerFunc(s.serve)))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() { s.srv.Close() }
+
+// Client returns an HTTP client configured for making requests to the server.
+func (s *Server) Client() *http.Client { return s.srv.Client() }
+
+// Register registers fn as the R function pkg::name, replacing any existing
+// registration.
+func (s *Server) Register(pkg, name string, fn Func) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.funcs[pkg] == nil {
+		s.funcs[pkg] = make(map[string]Func)
+	}
+	s.funcs[pkg][name] = fn
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Ocpu-Version", Version)
+	w.Header().Set("X-Ocpu-Server", "ocputest")
+	p := strings.TrimPrefix(r.URL.Path, "/")
+	elems := strings.Split(strings.TrimSuffix(p, "/"), "/")
+	switch {
+	case p == "":
+		fmt.Fprintln(w, "OpenCPU test server")
+	case p == "info" || p == "info/":
+		io.WriteString(w, sessionInfo)
+	case elems[0] == "library":
+		s.serveLibrary(w, r, elems[1:])
+	case elems[0] == "tmp" && len(elems) > 1:
+		s.serveSession(w, r, elems[1], elems[2:])
+	case p == "apps" || p == "apps/":
+		// No apps are installed.
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveLibrary(w http.ResponseWriter, r *http.Request, elems []string) {
+	if len(elems) >= 2 && elems[0] == "datasets" && elems[1] == "data" {
+		serveData(w, r, elems[2:])
+		return
+	}
+	s.mu.Lock()
+	var fns map[string]Func
+	if len(elems) != 0 {
+		fns = s.funcs[elems[0]]
+	}
+	switch {
+	case len(elems) == 0:
+		list(w, s.funcs)
+		s.mu.Unlock()
+		return
+	case fns == nil:
+		s.mu.Unlock()
+		http.NotFound(w, r)
+		return
+	case len(elems) == 1:
+		s.mu.Unlock()
+		fmt.Fprintln(w, "R")
+		return
+	case len(elems) == 2 && elems[1] == "R":
+		list(w, fns)
+		s.mu.Unlock()
+		return
+	case len(elems) < 3 || len(elems) > 4 || elems[1] != "R":
+		s.mu.Unlock()
+		http.NotFound(w, r)
+		return
+	}
+	pkg, name := elems[0], elems[2]
+	fn, ok := fns[name]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		fmt.Fprintf(w, "function (...) \n<ocputest: %s::%s>\n", pkg, name)
+		return
+	}
+	var format string
+	if len(elems) == 4 {
+		format = elems[3]
+	}
+	s.call(w, r, pkg, name, fn, format)
+}
+
+// datasets holds the datasets of the datasets package.
+var datasets = map[string]interface{}{
+	// The first rows of cars.
+	"cars": []interface{}{
+		map[string]interface{}{"speed": 4.0, "dist": 2.0},
+		map[string]interface{}{"speed": 4.0, "dist": 10.0},
+		map[string]interface{}{"speed": 7.0, "dist": 4.0},
+	},
+}
+
+// serveData handles a GET of the datasets in the datasets package.
+func serveData(w http.ResponseWriter, r *http.Request, elems []string) {
+	if len(elems) == 0 {
+		list(w, datasets)
+		return
+	}
+	v, ok := datasets[elems[0]]
+	if !ok || len(elems) > 2 {
+		http.NotFound(w, r)
+		return
+	}
+	format := "print"
+	if len(elems) == 2 {
+		format = elems[1]
+	}
+	writeFormat(w, r, v, format)
+}
+
+// list writes the sorted keys of the map m, one per line.
+func list(w io.Writer, m interface{}) {
+	var keys []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintln(w, k)
+	}
+}
+
+var keyPattern = regexp.MustCompile(`^x[0-9a-f]{10,}$`)
+
+// call handles a POST to the function pkg::name.
+func (s *Server) call(w http.ResponseWriter, r *http.Request, pkg, name string, fn Func, format string) {
+	c := Call{Args: make(map[string]interface{}), Files: make(map[string][]byte)}
+	err := s.parseArgs(r, &c)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var stdout bytes.Buffer
+	c.Stdout = &stdout
+	val, err := fn(&c)
+	if err != nil {
+		http.Error(w, "Error: "+err.Error()+"\n\nIn call:\n"+source(pkg, name, c.Args), http.StatusBadRequest)
+		return
+	}
+	if format == "" && stdout.Len() == 0 && val != nil {
+		stdout.WriteString(printValue(val))
+	}
+
+	sess := &session{
+		key:     newKey(),
+		name:    name,
+		source:  source(pkg, name, c.Args),
+		value:   val,
+		stdout:  stdout.String(),
+		warns:   c.Warnings,
+		msgs:    c.Messages,
+		files:   c.Files,
+		created: time.Now(),
+	}
+	sess.files["DESCRIPTION"] = []byte(fmt.Sprintf("Package: %s\nType: Session\nVersion: %s\nAuthor: OpenCPU\nDate: %s\nDescription: This file is automatically generated by OpenCPU.\n",
+		sess.key, Version, sess.created.Format("2006-01-02")))
+	s.mu.Lock()
+	s.sessions[sess.key] = sess
+	s.mu.Unlock()
+
+	w.Header().Set("X-Ocpu-Session", sess.key)
+	w.Header().Set("Location", s.URL+"/ocpu/tmp/"+sess.key+"/")
+	if format != "" {
+		writeFormat(w, r, val, format)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	for _, p := range sess.paths() {
+		fmt.Fprintln(w, "/ocpu/"+p)
+	}
+}
+
+// paths returns the paths of the session's objects relative to the
+// OpenCPU root.
+func (sess *session) paths() []string {
+	prefix := "tmp/" + sess.key + "/"
+	p := []string{prefix + "R/" + sess.name, prefix + "R/.val"}
+	for _, f := range []string{"stdout", "source", "console", "info"} {
+		p = append(p, prefix+f)
+	}
+	if len(sess.warns) != 0 {
+		p = append(p, prefix+"warnings")
+	}
+	if len(sess.msgs) != 0 {
+		p = append(p, prefix+"messages")
+	}
+	files := make([]string, 0, len(sess.files))
+	for f := range sess.files {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		p = append(p, prefix+"files/"+f)
+	}
+	return p
+}
+
+// newKey returns a new random session key.
+func newKey() string {
+	var b [5]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		panic(err)
+	}
+	return "x0" + hex.EncodeToString(b[:])
+}
+
+// parseArgs parses the arguments of r into c.
+func (s *Server) parseArgs(r *http.Request, c *Call) error {
+	typ, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil && r.ContentLength != 0 {
+		return fmt.Errorf("invalid content type: %v", err)
+	}
+	switch typ {
+	case "application/json":
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(b)) == 0 {
+			return nil
+		}
+		return json.Unmarshal(b, &c.Args)
+	case "application/x-www-form-urlencoded", "":
+		err := r.ParseForm()
+		if err != nil {
+			return err
+		}
+		return s.parseValues(r.PostForm, c)
+	case "multipart/form-data":
+		err := r.ParseMultipartForm(32 << 20)
+		if err != nil {
+			return err
+		}
+		err = s.parseValues(r.MultipartForm.Value, c)
+		if err != nil {
+			return err
+		}
+		for name, fhs := range r.MultipartForm.File {
+			for _, fh := range fhs {
+				f, err := fh.Open()
+				if err != nil {
+					return err
+				}
+				b, err := ioutil.ReadAll(f)
+				f.Close()
+				if err != nil {
+					return err
+				}
+				filename := path.Base(fh.Filename)
+				c.Files[filename] = b
+				c.Args[name] = filename
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported content type: %q", typ)
+	}
+}
+
+// parseValues parses URL encoded or multipart field values into c.
+func (s *Server) parseValues(v url.Values, c *Call) error {
+	for name, vals := range v {
+		if len(vals) == 0 {
+			continue
+		}
+		val, err := s.parseArg(vals[0])
+		if err != nil {
+			return fmt.Errorf("invalid argument %s: %v", name, err)
+		}
+		c.Args[name] = val
+	}
+	return nil
+}
+
+// callPattern matches a call of an R function with its arguments.
+var callPattern = regexp.MustCompile(`^(?:([A-Za-z.][A-Za-z0-9._]*)::)?([A-Za-z.][A-Za-z0-9._]*)\(([\s\S]*)\)$`)
+
+// parseArg parses a URL encoded or multipart argument. OpenCPU would
+// evaluate these as R expressions.
+func (s *Server) parseArg(arg string) (interface{}, error) {
+	arg = strings.TrimSpace(arg)
+	switch arg {
+	case "TRUE", "T", "true":
+		return true, nil
+	case "FALSE", "F", "false":
+		return false, nil
+	case "NULL", "null":
+		return nil, nil
+	}
+	if keyPattern.MatchString(arg) {
+		s.mu.Lock()
+		sess, ok := s.sessions[arg]
+		s.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("object '%s' not found", arg)
+		}
+		return sess.value, nil
+	}
+	var v interface{}
+	err := json.Unmarshal([]byte(arg), &v)
+	if err == nil {
+		return v, nil
+	}
+	if m := callPattern.FindStringSubmatch(arg); m != nil {
+		fn := s.lookup(m[1], m[2])
+		args, ok := splitArgs(m[3])
+		if fn != nil && ok {
+			return s.eval(fn, args)
+		}
+	}
+	return nil, fmt.Errorf("ocputest cannot evaluate R expression: %q", arg)
+}
+
+// lookup returns the registered function pkg::name. If pkg is empty,
+// base is searched first and then the other packages in lexical order.
+func (s *Server) lookup(pkg, name string) Func {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pkg != "" {
+		return s.funcs[pkg][name]
+	}
+	if fn, ok := s.funcs["base"][name]; ok {
+		return fn
+	}
+	pkgs := make([]string, 0, len(s.funcs))
+	for p := range s.funcs {
+		pkgs = append(pkgs, p)
+	}
+	sort.Strings(pkgs)
+	for _, p := range pkgs {
+		if fn, ok := s.funcs[p][name]; ok {
+			return fn
+		}
+	}
+	return nil
+}
+
+// eval calls fn with the arguments in args, returning its value. Arguments are parsed by parseArg and may be named with
+// "name = value". Since the formals of fn are not known, positional
+// arguments are named x, x2, x3 and so on. The value is returned as
+// decoded JSON.
+func (s *Server) eval(fn Func, args []string) (interface{}, error) {
+	c := Call{Args: make(map[string]interface{}), Files: make(map[string][]byte), Stdout: ioutil.Discard}
+	var pos int
+	for _, arg := range args {
+		if strings.TrimSpace(arg) == "" {
+			continue
+		}
+		name := ""
+		if m := argNamePattern.FindStringSubmatch(arg); m != nil {
+			name, arg = strings.Trim(m[1], "`"), m[2]
+		} else {
+			pos++
+			name = "x"
+			if pos > 1 {
+				name = fmt.Sprintf("x%d", pos)
+			}
+		}
+		v, err := s.parseArg(arg)
+		if err != nil {
+			return nil, err
+		}
+		c.Args[name] = v
+	}
+	v, err := fn(&c)
+	if err != nil {
+		return nil, err
+	}
+	// Arguments are passed to Funcs as decoded JSON.
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var val interface{}
+	err = json.Unmarshal(b, &val)
+	return val, err
+}
+
+// argNamePattern matches a named argument of a call.
+var argNamePattern = regexp.MustCompile(`^\s*([A-Za-z.][A-Za-z0-9._]*|` + "`[^`]+`" + `)\s*=([^=][\s\S]*)$`)
+
+// splitArgs splits the arguments of a call at commas that are not
+// within brackets or strings. It returns false if the brackets and
+// quotes of args are not balanced, as when the arguments are not
+// those of a single call.
+func splitArgs(args string) (parts []string, ok bool) {
+	var (
+		depth int
+		quote byte
+		start int
+	)
+	for i := 0; i < len(args); i++ {
+		c := args[i]
+		switch {
+		case quote != 0:
+			switch c {
+			case '\\':
+				i++
+			case quote:
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+			if depth < 0 {
+				return nil, false
+			}
+		case c == ',' && depth == 0:
+			parts = append(parts, args[start:i])
+			start = i + 1
+		}
+	}
+	if depth != 0 || quote != 0 {
+		return nil, false
+	}
+	return append(parts, args[start:]), true
+}
+
+// source returns an R-like representation of a call.
+func source(pkg, name string, args map[string]interface{}) string {
+	names := make([]string, 0, len(args))
+	for k := range args {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s::%s(", pkg, name)
+	for i, k := range names {
+		if i != 0 {
+			b.WriteString(", ")
+		}
+		v, _ := json.Marshal(args[k])
+		fmt.Fprintf(&b, "%s = %s", k, v)
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+func (s *Server) serveSession(w http.ResponseWriter, r *http.Request, key string, elems []string) {
+	s.mu.Lock()
+	sess, ok := s.sessions[key]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "Session not found: "+key, http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "ocputest only supports GET on sessions", http.StatusMethodNotAllowed)
+		return
+	}
+	if len(elems) == 0 {
+		for _, p := range sess.paths() {
+			fmt.Fprintln(w, "/ocpu/"+p)
+		}
+		return
+	}
+	switch elems[0] {
+	case "R":
+		switch {
+		case len(elems) == 2 && elems[1] == sess.name:
+			fmt.Fprintf(w, "function (...) \n<ocputest: %s>\n", sess.name)
+			return
+		case len(elems) < 2 || len(elems) > 3 || elems[1] != ".val":
+			http.NotFound(w, r)
+			return
+		}
+		if len(elems) == 2 {
+			io.WriteString(w, printValue(sess.value))
+			return
+		}
+		writeFormat(w, r, sess.value, elems[2])
+	case "stdout":
+		io.WriteString(w, sess.stdout)
+	case "source":
+		fmt.Fprintln(w, sess.source)
+	case "console":
+		fmt.Fprintf(w, "> %s\n%s", sess.source, sess.stdout)
+	case "info":
+		io.WriteString(w, sessionInfo)
+	case "warnings":
+		for _, m := range sess.warns {
+			fmt.Fprintln(w, m)
+		}
+	case "messages":
+		for _, m := range sess.msgs {
+			fmt.Fprintln(w, m)
+		}
+	case "files":
+		if len(elems) == 1 {
+			list(w, sess.files)
+			return
+		}
+		b, ok := sess.files[path.Join(elems[1:]...)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// writeFormat writes v to w in the named format.
+func writeFormat(w http.ResponseWriter, r *http.Request, v interface{}, format string) {
+	var (
+		b   []byte
+		typ string
+		err error
+	)
+	switch format {
+	case "json":
+		b, err = encodeJSON(v, r.URL.Query())
+		typ = "application/json"
+	case "ndjson":
+		b, err = encodeNDJSON(v)
+		typ = "application/x-ndjson"
+	case "csv":
+		b, err = encodeCSV(v, ',')
+		typ = "text/csv"
+	case "tab":
+		b, err = encodeCSV(v, '\t')
+		typ = "text/plain"
+	case "text", "print":
+		b = []byte(printValue(v))
+		typ = "text/plain"
+	default:
+		err = errors.New("ocputest: unsupported format: " + format)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", typ)
+	w.Write(b)
+}
+
+const sessionInfo = `R version 4.3.1 (2023-06-16)
+Platform: x86_64-pc-linux-gnu (64-bit)
+Running under: ocputest
+
+Matrix products: default
+
+locale:
+[1] C
+
+attached base packages:
+[1] stats     graphics  grDevices utils     datasets  methods   base     
+
+other attached packages:
+[1] opencpu_` + Version + `
+`
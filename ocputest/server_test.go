@@ -0,0 +1,227 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ocputest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kortschak/arrgh"
+	"github.com/kortschak/arrgh/ocputest"
+)
+
+func newSession(t *testing.T, srv *ocputest.Server) *arrgh.Session {
+	t.Helper()
+	s, err := arrgh.NewRemoteSession(srv.URL, "", 10*time.Second)
+	if err != nil {
+		t.Fatalf("failed to connect to test server: %v", err)
+	}
+	return s
+}
+
+func TestServer(t *testing.T) {
+	srv := ocputest.NewServer()
+	defer srv.Close()
+	srv.Register("stats", "rnorm", func(c *ocputest.Call) (interface{}, error) {
+		n, _ := c.Args["n"].(float64)
+		mean, _ := c.Args["mean"].(float64)
+		fmt.Fprintln(c.Stdout, "generating", n, "values")
+		v := make([]float64, int(n))
+		for i := range v {
+			v[i] = mean
+		}
+		return v, nil
+	})
+	s := newSession(t, srv)
+	ctx := context.Background()
+
+	stats := arrgh.Package{Library: arrgh.SystemLibrary, Name: "stats"}
+	v, err := s.Invoke(ctx, arrgh.Function{Package: stats, Name: "rnorm"}, map[string]interface{}{"n": 3, "mean": 10}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error invoking rnorm: %v", err)
+	}
+	if want := "[10,10,10]\n"; string(v) != want {
+		t.Errorf("unexpected rnorm value: got:%q want:%q", v, want)
+	}
+
+	// Chain a session key through a URL encoded call.
+	res, err := s.Call(ctx, "library/base/R/identity", "application/x-www-form-urlencoded", nil, strings.NewReader("x=[1,2,3]"))
+	if err != nil {
+		t.Fatalf("unexpected error calling identity: %v", err)
+	}
+	if !strings.HasPrefix(res.Key, "x") || len(res.Paths) == 0 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	sum, err := s.Call(ctx, "library/base/R/sum", "application/x-www-form-urlencoded", nil, strings.NewReader("x="+res.Key))
+	if err != nil {
+		t.Fatalf("unexpected error calling sum: %v", err)
+	}
+	r, err := s.Object(ctx, "tmp/"+sum.Key+"/R/.val", arrgh.JSON, nil)
+	if err != nil {
+		t.Fatalf("unexpected error getting sum: %v", err)
+	}
+	b, _ := ioutil.ReadAll(r)
+	r.Close()
+	if want := "[6]\n"; string(b) != want {
+		t.Errorf("unexpected sum: got:%q want:%q", b, want)
+	}
+	for path, want := range map[string]string{
+		"tmp/" + sum.Key + "/stdout":  "[1] 6\n",
+		"tmp/" + sum.Key + "/source":  "base::sum(x = [1,2,3])\n",
+		"tmp/" + sum.Key + "/console": "> base::sum(x = [1,2,3])\n[1] 6\n",
+	} {
+		resp, err := s.Get(path, nil)
+		if err != nil {
+			t.Fatalf("unexpected error getting %s: %v", path, err)
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(b) != want {
+			t.Errorf("unexpected content for %s: got:%q want:%q", path, b, want)
+		}
+	}
+
+	// Evaluate calls of registered functions in URL encoded arguments.
+	expr := "sum(" + res.Key + ", stats::rnorm(n = 2, mean = 1), na.rm = TRUE)"
+	call, err := s.Call(ctx, "library/base/R/identity", "application/x-www-form-urlencoded", nil, strings.NewReader("x="+url.QueryEscape(expr)))
+	if err != nil {
+		t.Fatalf("unexpected error evaluating %s: %v", expr, err)
+	}
+	r, err = s.Object(ctx, "tmp/"+call.Key+"/R/.val", arrgh.JSON, nil)
+	if err != nil {
+		t.Fatalf("unexpected error getting value of %s: %v", expr, err)
+	}
+	b, _ = ioutil.ReadAll(r)
+	r.Close()
+	if want := "[8]\n"; string(b) != want {
+		t.Errorf("unexpected value of %s: got:%q want:%q", expr, b, want)
+	}
+
+	// Upload a file.
+	f, err := os.Open("../mydata.csv")
+	if err != nil {
+		t.Fatalf("failed to open test file: %v", err)
+	}
+	defer f.Close()
+	content, body, err := arrgh.Multipart(arrgh.Params{"header": "FALSE"}, arrgh.Files{"file": f})
+	if err != nil {
+		t.Fatalf("unexpected error building multipart body: %v", err)
+	}
+	csv, err := s.Call(ctx, "library/utils/R/read.csv", content, nil, body)
+	if err != nil {
+		t.Fatalf("unexpected error calling read.csv: %v", err)
+	}
+	files, err := s.Files(ctx, csv.Key)
+	if err != nil {
+		t.Fatalf("unexpected error listing files: %v", err)
+	}
+	if want := []string{"DESCRIPTION", "mydata.csv"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("unexpected files: got:%q want:%q", files, want)
+	}
+	r, err = s.Object(ctx, "tmp/"+csv.Key+"/R/.val", arrgh.CSV, nil)
+	if err != nil {
+		t.Fatalf("unexpected error getting csv: %v", err)
+	}
+	b, _ = ioutil.ReadAll(r)
+	r.Close()
+	if !strings.HasPrefix(string(b), "V1,V2") {
+		t.Errorf("unexpected csv: got:%q", b)
+	}
+	r, err = s.OpenFile(ctx, csv.Key, "DESCRIPTION")
+	if err != nil {
+		t.Fatalf("unexpected error opening DESCRIPTION: %v", err)
+	}
+	d, err := arrgh.ParseDescription(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("unexpected error parsing DESCRIPTION: %v", err)
+	}
+	if d.Package != csv.Key || d.Type != "Session" {
+		t.Errorf("unexpected description: %+v", d)
+	}
+
+	// Errors.
+	_, err = s.Call(ctx, "library/base/R/stop", "application/json", nil, strings.NewReader(`{"msg":"boom"}`))
+	var e *arrgh.Error
+	if !errors.As(err, &e) || !strings.Contains(e.Message, "boom") {
+		t.Errorf("unexpected error for stop: %v", err)
+	}
+	_, err = s.Call(ctx, "library/base/R/identity", "application/x-www-form-urlencoded", nil, strings.NewReader("x="+url.QueryEscape("coef(lm(speed ~ dist))")))
+	if !errors.As(err, &e) || e.StatusCode != 400 {
+		t.Errorf("unexpected error for R expression: %v", err)
+	}
+
+	// Info and version.
+	info, err := s.Info(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error getting info: %v", err)
+	}
+	if info.OpenCPUVersion != ocputest.Version {
+		t.Errorf("unexpected OpenCPU version: got:%q want:%q", info.OpenCPUVersion, ocputest.Version)
+	}
+	fns, err := s.Functions(ctx, stats)
+	if err != nil {
+		t.Fatalf("unexpected error listing functions: %v", err)
+	}
+	if want := []arrgh.Function{{Package: stats, Name: "rnorm"}}; !reflect.DeepEqual(fns, want) {
+		t.Errorf("unexpected functions: got:%v want:%v", fns, want)
+	}
+}
+
+func TestServerCalls(t *testing.T) {
+	srv := ocputest.NewServer()
+	defer srv.Close()
+	s := newSession(t, srv)
+	ctx := context.Background()
+
+	x, err := s.Call(ctx, "library/base/R/identity", "application/json", nil, strings.NewReader(`{"x":[1,2,3]}`))
+	if err != nil {
+		t.Fatalf("unexpected error calling identity: %v", err)
+	}
+	for _, test := range []struct {
+		expr    string
+		want    string
+		wantErr string
+	}{
+		{expr: "sum(" + x.Key + ")", want: "[6]\n"},
+		{expr: "sum(" + x.Key + ", sum(1, [2,3]), na.rm = TRUE)", want: "[12]\n"},
+		{expr: "base::identity(x = sum(identity(" + x.Key + "), 1))", want: "[7]\n"},
+		{expr: `identity("a, (b)")`, want: "[\"a, (b)\"]\n"},
+		{expr: `base::stop("boom")`, wantErr: "boom"},
+		{expr: "sum(identity(x0000000000))", wantErr: "object 'x0000000000' not found"},
+		{expr: "sum(mean(1))", wantErr: "cannot evaluate R expression"},
+		{expr: "sum(1) + sum(2)", wantErr: "cannot evaluate R expression"},
+	} {
+		res, err := s.Call(ctx, "library/base/R/identity", "application/x-www-form-urlencoded", nil, strings.NewReader("x="+url.QueryEscape(test.expr)))
+		if test.wantErr != "" {
+			var e *arrgh.Error
+			if !errors.As(err, &e) || !strings.Contains(e.Message, test.wantErr) {
+				t.Errorf("unexpected error for %s: got:%v want:%s", test.expr, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", test.expr, err)
+			continue
+		}
+		r, err := s.Object(ctx, "tmp/"+res.Key+"/R/.val", arrgh.JSON, nil)
+		if err != nil {
+			t.Fatalf("unexpected error getting value of %s: %v", test.expr, err)
+		}
+		b, _ := ioutil.ReadAll(r)
+		r.Close()
+		if string(b) != test.want {
+			t.Errorf("unexpected value of %s: got:%q want:%q", test.expr, b, test.want)
+		}
+	}
+}
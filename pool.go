@@ -0,0 +1,256 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	pth "path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Pool is a set of local OpenCPU servers that share the work of a session.
+// The methods of the embedded Session send each new call to the server with
+// the fewest requests in progress. Since the servers do not share session
+// storage, requests that refer to a session key are sent to the server that
+// created the key.
+//
+// The health of the servers is checked periodically, and a server that
+// does not respond to several consecutive checks is shut down. A server
+// whose R process exits is replaced by a new server. Session keys created
+// by the lost server can no longer be used, and requests that refer to
+// them fail.
+//
+// Session keys are found in the path and parameters of requests, and in
+// request bodies of known length up to 1MiB. Requests whose only reference
+// to a session key is in a larger body, or one of unknown length such as a
+// streamed multipart body, are not sent to the server that created the key.
+//
+// It is important that Close be called on pools returned by NewPool.
+type Pool struct {
+	*Session
+
+	router *router
+
+	// start starts a member of the pool.
+	start func() (*Session, error)
+
+	done   chan struct{}
+	closed bool // Protected by router.mu.
+	wg     sync.WaitGroup
+}
+
+const (
+	// healthInterval is the time between health
+	// checks of the servers of a pool.
+	healthInterval = 10 * time.Second
+
+	// maxMisses is the number of consecutive health
+	// checks a server may fail before it is shut down.
+	maxMisses = 3
+)
+
+// NewPool starts n local OpenCPU servers on free ports, as described for
+// NewLocalSession, and returns a pool that distributes calls among them.
+// The servers' logs are all written to log.
+func NewPool(path, root string, n int, timeout time.Duration, log io.Writer) (*Pool, error) {
+	return newPool(root, n, healthInterval, func() (*Session, error) {
+		port, err := freePort()
+		if err != nil {
+			return nil, err
+		}
+		return NewLocalSession(path, root, port, timeout, log)
+	})
+}
+
+// newPool returns a pool of n servers started by start, checking the
+// health of the servers at the given interval.
+func newPool(root string, n int, interval time.Duration, start func() (*Session, error)) (*Pool, error) {
+	if n < 1 {
+		return nil, errors.New("arrgh: pool must have at least one server")
+	}
+	if root == "" {
+		root = "ocpu"
+	}
+	p := &Pool{
+		router: &router{
+			root:      pth.Join("/", root),
+			transport: http.DefaultTransport,
+			keys:      make(map[string]*issue),
+			order:     leastBusy,
+		},
+		start: start,
+		done:  make(chan struct{}),
+	}
+	p.Session = newRoutedSession(p.router)
+
+	sessions := make([]*Session, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range sessions {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sessions[i], errs[i] = start()
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			for _, s := range sessions {
+				if s != nil {
+					s.Close()
+				}
+			}
+			return nil, err
+		}
+	}
+	for _, s := range sessions {
+		p.add(s)
+	}
+	p.wg.Add(1)
+	go p.check(interval)
+	return p, nil
+}
+
+// Len returns the number of servers in the pool.
+func (p *Pool) Len() int {
+	p.router.mu.Lock()
+	defer p.router.mu.Unlock()
+	return len(p.router.backends)
+}
+
+// add adds the server of s to the pool and watches it for failure.
+// If the pool has been closed, s is closed.
+func (p *Pool) add(s *Session) {
+	p.router.mu.Lock()
+	defer p.router.mu.Unlock()
+	if p.closed {
+		s.Close()
+		return
+	}
+	b := &backend{base: s.host, sess: s}
+	p.router.backends = append(p.router.backends, b)
+	p.wg.Add(1)
+	go p.watch(b)
+}
+
+// watch waits for the R process of b to exit and replaces b with a
+// new server unless the pool has been closed.
+func (p *Pool) watch(b *backend) {
+	defer p.wg.Done()
+	b.sess.cmd.Wait()
+
+	p.router.mu.Lock()
+	p.remove(b)
+	p.router.forget(b)
+	p.router.mu.Unlock()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		default:
+		}
+		s, err := p.start()
+		if err == nil {
+			p.add(s)
+			return
+		}
+		select {
+		case <-p.done:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// remove removes b from the servers of the pool, returning whether it
+// was found. It is called with router.mu held.
+func (p *Pool) remove(b *backend) bool {
+	for i, m := range p.router.backends {
+		if m == b {
+			p.router.backends = append(p.router.backends[:i], p.router.backends[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// check checks the health of the pool's servers at the given interval
+// until the pool is closed. A server that fails maxMisses consecutive
+// checks is shut down, so that it is replaced by watch.
+func (p *Pool) check(interval time.Duration) {
+	defer p.wg.Done()
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-tick.C:
+		}
+		p.router.mu.Lock()
+		backends := append([]*backend(nil), p.router.backends...)
+		p.router.mu.Unlock()
+		for _, b := range backends {
+			ok := ping(b.base.String())
+			p.router.mu.Lock()
+			if ok {
+				b.misses = 0
+			} else {
+				b.misses++
+			}
+			// An unhealthy server is removed from the pool
+			// so that Close does not also shut it down. The
+			// pool may have been closed during the check.
+			unhealthy := b.misses == maxMisses && !p.closed && p.remove(b)
+			p.router.mu.Unlock()
+			if unhealthy {
+				b.sess.Close()
+			}
+		}
+	}
+}
+
+// Close shuts down the servers of the pool.
+func (p *Pool) Close() error {
+	p.router.mu.Lock()
+	if p.closed {
+		p.router.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.done)
+	backends := p.router.backends
+	p.router.backends = nil
+	p.router.mu.Unlock()
+
+	var err error
+	for _, b := range backends {
+		cerr := b.sess.Close()
+		if err == nil {
+			err = cerr
+		}
+	}
+	p.wg.Wait()
+	return err
+}
+
+// leastBusy returns the backends ordered by the number of requests they
+// are handling, with those that failed their last health check last.
+func leastBusy(backends []*backend) []*backend {
+	order := append([]*backend(nil), backends...)
+	sort.SliceStable(order, func(i, j int) bool {
+		if (order[i].misses == 0) != (order[j].misses == 0) {
+			return order[i].misses == 0
+		}
+		return order[i].active < order[j].active
+	})
+	return order
+}
@@ -0,0 +1,242 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package arrgh
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kortschak/arrgh/ocputest"
+)
+
+func TestPool(t *testing.T) {
+	// Each member of the pool is an independent ocputest server
+	// with a sleep process standing in for R.
+	var (
+		mu      sync.Mutex
+		members []*Session
+	)
+	p, err := newPool("", 2, time.Hour, func() (*Session, error) {
+		srv := ocputest.NewServer()
+		t.Cleanup(srv.Close)
+		cmd := exec.Command("sleep", "60")
+		err := cmd.Start()
+		if err != nil {
+			return nil, err
+		}
+		s := testSession(t, srv.URL)
+		s.cmd = cmd
+		mu.Lock()
+		members = append(members, s)
+		mu.Unlock()
+		return s, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error starting pool: %v", err)
+	}
+	defer p.Close()
+	if p.Len() != 2 {
+		t.Fatalf("unexpected pool size: got:%d want:2", p.Len())
+	}
+	ctx := context.Background()
+
+	// busy adds n to the number of requests in progress on member i.
+	busy := func(i, n int) {
+		p.router.mu.Lock()
+		p.router.backends[i].active += n
+		p.router.mu.Unlock()
+	}
+
+	// Keys are only valid on the member that created them, so
+	// chained calls fail unless they are routed there, even when
+	// another member is less busy.
+	var keys []string
+	for i := 0; i < 2; i++ {
+		busy(1-i, 1)
+		x, err := p.Call(ctx, "library/base/R/identity", "application/json", nil, strings.NewReader(`{"x":[1,2,3,4]}`))
+		busy(1-i, -1)
+		if err != nil {
+			t.Fatalf("unexpected error calling identity: %v", err)
+		}
+		keys = append(keys, x.Key)
+
+		busy(i, 1)
+		res, err := p.Call(ctx, "library/base/R/sum", "application/x-www-form-urlencoded", nil, strings.NewReader("x="+x.Key))
+		busy(i, -1)
+		if err != nil {
+			t.Fatalf("unexpected error calling sum with session key: %v", err)
+		}
+		r, err := p.Object(ctx, "tmp/"+res.Key+"/R/.val", JSON, nil)
+		if err != nil {
+			t.Fatalf("unexpected error getting sum: %v", err)
+		}
+		var sum []float64
+		err = json.NewDecoder(r).Decode(&sum)
+		r.Close()
+		if err != nil {
+			t.Fatalf("unexpected error decoding sum: %v", err)
+		}
+		if !reflect.DeepEqual(sum, []float64{10}) {
+			t.Errorf("unexpected sum: got:%v want:[10]", sum)
+		}
+	}
+	p.router.mu.Lock()
+	if p.router.issuer(keys[0]) == p.router.issuer(keys[1]) {
+		t.Error("calls not distributed across pool")
+	}
+	p.router.mu.Unlock()
+
+	// Simulate R crashing on the member that created the first key.
+	p.router.mu.Lock()
+	lost := p.router.issuer(keys[0]).sess
+	p.router.mu.Unlock()
+	err = lost.cmd.Process.Kill()
+	if err != nil {
+		t.Fatalf("failed to kill process: %v", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		mu.Lock()
+		n := len(members)
+		mu.Unlock()
+		if n == 3 && p.Len() == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("pool member not replaced")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The lost member's keys are forgotten, and no
+	// remaining member holds their sessions.
+	p.router.mu.Lock()
+	if p.router.issuer(keys[0]) != nil {
+		t.Error("lost member's key not forgotten")
+	}
+	if p.router.issuer(keys[1]) == nil {
+		t.Error("remaining member's key forgotten")
+	}
+	p.router.mu.Unlock()
+	_, err = p.Call(ctx, "library/base/R/sum", "application/x-www-form-urlencoded", nil, strings.NewReader("x="+keys[0]))
+	want := "object '" + keys[0] + "' not found"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("unexpected error for lost session key: got:%v want:%s", err, want)
+	}
+	for i := 0; i < 4; i++ {
+		_, err = p.Call(ctx, "library/base/R/identity", "application/json", nil, strings.NewReader(`{"x":1}`))
+		if err != nil {
+			t.Fatalf("unexpected error calling identity after replacement: %v", err)
+		}
+	}
+
+	err = p.Close()
+	if err != nil {
+		t.Errorf("unexpected error closing pool: %v", err)
+	}
+	if p.Len() != 0 {
+		t.Errorf("unexpected pool size after close: %d", p.Len())
+	}
+}
+
+func TestPoolHealth(t *testing.T) {
+	// The first member's server stops responding
+	// while its R process is still running.
+	var (
+		mu      sync.Mutex
+		members []*Session
+		servers []*ocputest.Server
+	)
+	p, err := newPool("", 1, 10*time.Millisecond, func() (*Session, error) {
+		srv := ocputest.NewServer()
+		t.Cleanup(srv.Close)
+		cmd := exec.Command("sleep", "60")
+		err := cmd.Start()
+		if err != nil {
+			return nil, err
+		}
+		s := testSession(t, srv.URL)
+		s.cmd = cmd
+		mu.Lock()
+		members = append(members, s)
+		servers = append(servers, srv)
+		mu.Unlock()
+		return s, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error starting pool: %v", err)
+	}
+	defer p.Close()
+	mu.Lock()
+	servers[0].Close()
+	mu.Unlock()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		mu.Lock()
+		n := len(members)
+		mu.Unlock()
+		if n == 2 && p.Len() == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("unresponsive pool member not replaced")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if members[0].cmd.ProcessState == nil {
+		t.Error("unresponsive pool member not shut down")
+	}
+	_, err = p.Call(context.Background(), "library/base/R/identity", "application/json", nil, strings.NewReader(`{"x":1}`))
+	if err != nil {
+		t.Errorf("unexpected error calling identity after replacement: %v", err)
+	}
+}
+
+func TestPoolHealthClose(t *testing.T) {
+	// Health checks shut down unresponsive members
+	// while the pool is being closed.
+	for i := 0; i < 20; i++ {
+		p, err := newPool("", 4, time.Microsecond, func() (*Session, error) {
+			srv := ocputest.NewServer()
+			srv.Close()
+			cmd := exec.Command("sleep", "60")
+			err := cmd.Start()
+			if err != nil {
+				return nil, err
+			}
+			s := testSession(t, srv.URL)
+			s.cmd = cmd
+			return s, nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error starting pool: %v", err)
+		}
+		time.Sleep(time.Duration(i) * 200 * time.Microsecond)
+		p.Close()
+	}
+}
+
+func TestLeastBusy(t *testing.T) {
+	a := &backend{active: 2}
+	b := &backend{active: 0}
+	c := &backend{active: 1}
+	d := &backend{active: 0}
+	e := &backend{active: 0, misses: 1}
+	got := leastBusy([]*backend{a, e, b, c, d})
+	want := []*backend{b, d, c, a, e}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected order: got:%v want:%v", got, want)
+	}
+}
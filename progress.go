@@ -0,0 +1,106 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Progress describes the state of a request's data transfer.
+type Progress struct {
+	// Sent is the number of bytes of the
+	// request body that have been sent.
+	Sent int64
+	// SendTotal is the length of the request
+	// body or -1 if it is not known.
+	SendTotal int64
+
+	// Received is the number of bytes of the
+	// response body that have been received.
+	Received int64
+	// ReceiveTotal is the length of the response
+	// body or -1 if it is not known.
+	ReceiveTotal int64
+}
+
+type progressKey struct{}
+
+// WithProgress returns a copy of ctx that causes requests made by Session
+// methods with the returned context to report their progress to fn. The
+// function is called each time data is sent or received, and may be called
+// concurrently with itself while a request body is being sent. To receive
+// progress on a channel, fn should perform a non-blocking send.
+func WithProgress(ctx context.Context, fn func(Progress)) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// progressFunc returns the progress function held by ctx or nil.
+func progressFunc(ctx context.Context) func(Progress) {
+	fn, _ := ctx.Value(progressKey{}).(func(Progress))
+	return fn
+}
+
+// progress tracks the transfer of a request and its response.
+type progress struct {
+	mu    sync.Mutex
+	state Progress
+	fn    func(Progress)
+}
+
+// newProgress returns a progress tracking the sending of req's body.
+func newProgress(req *http.Request, fn func(Progress)) *progress {
+	p := &progress{state: Progress{SendTotal: -1, ReceiveTotal: -1}, fn: fn}
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.ContentLength > 0 {
+			p.state.SendTotal = req.ContentLength
+		}
+		req.Body = &progressReader{ReadCloser: req.Body, add: p.sent}
+		// Replaying the body would confuse the counts.
+		req.GetBody = nil
+	}
+	return p
+}
+
+// receive arranges for progress of reading the resp body to be tracked.
+func (p *progress) receive(resp *http.Response) {
+	p.mu.Lock()
+	p.state.ReceiveTotal = resp.ContentLength
+	p.mu.Unlock()
+	resp.Body = &progressReader{ReadCloser: resp.Body, add: p.received}
+}
+
+func (p *progress) sent(n int) {
+	p.mu.Lock()
+	p.state.Sent += int64(n)
+	state := p.state
+	p.mu.Unlock()
+	p.fn(state)
+}
+
+func (p *progress) received(n int) {
+	p.mu.Lock()
+	p.state.Received += int64(n)
+	state := p.state
+	p.mu.Unlock()
+	p.fn(state)
+}
+
+// progressReader is an io.ReadCloser that reports the
+// number of bytes read to add.
+type progressReader struct {
+	io.ReadCloser
+	add func(int)
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	if n > 0 {
+		r.add(n)
+	}
+	return n, err
+}
@@ -0,0 +1,59 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+	defer srv.Close()
+	s := testSession(t, srv.URL)
+
+	const query = "Lorem ipsum dolor sit amet, consectetur adipiscing elit."
+	var (
+		mu   sync.Mutex
+		last Progress
+		n    int
+	)
+	ctx := WithProgress(context.Background(), func(p Progress) {
+		mu.Lock()
+		last = p
+		n++
+		mu.Unlock()
+	})
+	resp, err := s.PostContext(ctx, "library/base/R/identity", "text/plain", nil, strings.NewReader(query))
+	if err != nil {
+		t.Fatalf("unexpected error for POST: %v", err)
+	}
+	_, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("unexpected error reading response: %v", err)
+	}
+
+	want := Progress{
+		Sent:         int64(len(query)),
+		SendTotal:    int64(len(query)),
+		Received:     int64(len(query)),
+		ReceiveTotal: int64(len(query)),
+	}
+	if last != want {
+		t.Errorf("unexpected final progress: got:%+v want:%+v", last, want)
+	}
+	if n < 2 {
+		t.Errorf("unexpected number of progress reports: got:%d want at least 2", n)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	pth "path"
+	"strconv"
+	"strings"
+)
+
+// Error is an error response from an OpenCPU server.
+type Error struct {
+	// StatusCode and Status are the HTTP
+	// status code and status of the response.
+	StatusCode int
+	Status     string
+
+	// Message is the body of the response.
+	Message string
+}
+
+func (e *Error) Error() string {
+	msg := strings.TrimSpace(e.Message)
+	if msg == "" {
+		return fmt.Sprintf("arrgh: opencpu error: %s", e.Status)
+	}
+	return fmt.Sprintf("arrgh: opencpu error: %s: %s", e.Status, msg)
+}
+
+// checkResponse returns an *Error if resp has a non-success status,
+// consuming and closing the response body.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < 400 {
+		return nil
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	return &Error{StatusCode: resp.StatusCode, Status: resp.Status, Message: string(b)}
+}
+
+// Result is an OpenCPU session result.
+type Result struct {
+	// Key is the session key of the result.
+	Key string
+
+	// Paths holds the paths of the objects
+	// held by the session, relative to the
+	// OpenCPU root.
+	Paths []string
+}
+
+// Call sends the query content to the given OpenCPU path as the specified content
+// type using the POST method and returns the session result. The parameters are
+// interpreted as described for Post. If the server responds with an error status,
+// the returned error is an *Error.
+func (s *Session) Call(ctx context.Context, path, content string, params url.Values, query io.Reader) (*Result, error) {
+	resp, err := s.PostContext(ctx, path, content, params, query)
+	if err != nil {
+		return nil, err
+	}
+	err = checkResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return s.result(resp)
+}
+
+// result returns the session result described by the response to a POST.
+func (s *Session) result(resp *http.Response) (*Result, error) {
+	var res Result
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		p := strings.TrimSpace(sc.Text())
+		if p == "" {
+			continue
+		}
+		res.Paths = append(res.Paths, s.rel(p))
+	}
+	err := sc.Err()
+	if err != nil {
+		return nil, err
+	}
+	res.Key = resp.Header.Get("X-Ocpu-Session")
+	if res.Key == "" {
+		for _, p := range res.Paths {
+			if k := keyOf(p); k != "" {
+				res.Key = k
+				break
+			}
+		}
+	}
+	if res.Key == "" {
+		return nil, fmt.Errorf("arrgh: no session key in response from %s", resp.Request.URL)
+	}
+	return &res, nil
+}
+
+// rel returns the path p relative to the OpenCPU root.
+func (s *Session) rel(p string) string {
+	if u, err := url.Parse(p); err == nil && u.IsAbs() {
+		p = u.Path
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(p, s.root), "/")
+}
+
+// keyOf returns the session key of a path relative to the OpenCPU
+// root or the empty string if the path is not within a session.
+func keyOf(p string) string {
+	parts := strings.SplitN(p, "/", 3)
+	if len(parts) < 2 || parts[0] != "tmp" {
+		return ""
+	}
+	return parts[1]
+}
+
+// sessionPath returns the path of elem within the session with the given key.
+func sessionPath(key string, elem ...string) string {
+	return pth.Join(append([]string{"tmp", key}, elem...)...)
+}
+
+// Open returns a reader for the resource at the given OpenCPU path, for
+// example "tmp/x0123456789/stdout". If the server responds with an error
+// status, the returned error is an *Error. The returned reader must be
+// closed after use.
+func (s *Session) Open(ctx context.Context, path string, params url.Values) (io.ReadCloser, error) {
+	return s.open(ctx, path, params)
+}
+
+// open retrieves the given OpenCPU path, returning an *Error if the server
+// responds with an error status.
+func (s *Session) open(ctx context.Context, path string, params url.Values) (io.ReadCloser, error) {
+	resp, err := s.GetContext(ctx, path, params)
+	if err != nil {
+		return nil, err
+	}
+	err = checkResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// list returns the lines of the OpenCPU directory listing at path.
+func (s *Session) list(ctx context.Context, path string) ([]string, error) {
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	r, err := s.open(ctx, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var names []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		name := strings.TrimSpace(sc.Text())
+		if name == "" {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, sc.Err()
+}
+
+// evalJSON evaluates the R expression code on the server and decodes the
+// JSON encoding of the result into dst.
+func (s *Session) evalJSON(ctx context.Context, code string, dst interface{}) error {
+	resp, err := s.PostContext(ctx,
+		"library/base/R/identity/json",
+		"application/x-www-form-urlencoded",
+		nil,
+		strings.NewReader("x="+url.QueryEscape(code)),
+	)
+	if err != nil {
+		return err
+	}
+	err = checkResponse(resp)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
+
+// rQuote returns s as an R string literal.
+func rQuote(s string) string {
+	return strconv.Quote(s)
+}
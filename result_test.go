@@ -0,0 +1,85 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCall(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ocpu/library/base/R/identity":
+			w.Header().Set("X-Ocpu-Session", "x0123456789")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, "/ocpu/tmp/x0123456789/R/.val\n/ocpu/tmp/x0123456789/stdout\n")
+		case "/ocpu/library/base/R/stop":
+			http.Error(w, "boom", http.StatusBadRequest)
+		case "/ocpu/tmp/x0123456789/stdout":
+			fmt.Fprint(w, "[1] 1\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	s := testSession(t, srv.URL)
+
+	res, err := s.Call(context.Background(), "library/base/R/identity", "application/json", nil, strings.NewReader(`{"x":1}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &Result{Key: "x0123456789", Paths: []string{"tmp/x0123456789/R/.val", "tmp/x0123456789/stdout"}}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("unexpected result: got:%+v want:%+v", res, want)
+	}
+
+	_, err = s.Call(context.Background(), "library/base/R/stop", "application/json", nil, strings.NewReader(`{}`))
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("expected *Error: got:%T", err)
+	}
+	if e.StatusCode != http.StatusBadRequest || strings.TrimSpace(e.Message) != "boom" {
+		t.Errorf("unexpected error: got:%+v", e)
+	}
+
+	rc, err := s.Open(context.Background(), "tmp/x0123456789/stdout", nil)
+	if err != nil {
+		t.Fatalf("unexpected error opening stdout: %v", err)
+	}
+	b, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil || string(b) != "[1] 1\n" {
+		t.Errorf("unexpected stdout: got:%q err:%v", b, err)
+	}
+	_, err = s.Open(context.Background(), "tmp/x0123456789/missing", nil)
+	if !errors.As(err, &e) || e.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected error opening missing path: got:%v", err)
+	}
+}
+
+func TestKeyOf(t *testing.T) {
+	for _, test := range []struct {
+		path string
+		want string
+	}{
+		{path: "tmp/x0123456789/R/.val", want: "x0123456789"},
+		{path: "tmp/x0123456789", want: "x0123456789"},
+		{path: "library/base/R/identity", want: ""},
+		{path: "tmp", want: ""},
+	} {
+		got := keyOf(test.path)
+		if got != test.want {
+			t.Errorf("unexpected key for %q: got:%q want:%q", test.path, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	pth "path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backend is an OpenCPU server that a router sends requests to.
+type backend struct {
+	// base is the URL of the server's API root.
+	base *url.URL
+
+	// active is the number of requests
+	// being handled by the server.
+	active int
+
+	// sess is the session of a local
+	// server, if the backend is one.
+	sess *Session
+
+	// misses is the number of consecutive
+	// failed health checks of a local
+	// server.
+	misses int
+
+	// weight and current are the weight
+	// of a remote host and its state in
+	// weighted selection.
+	weight  int
+	current int
+
+	// retry is the time after which a
+	// remote host that has failed is
+	// again preferred for new calls.
+	retry time.Time
+}
+
+// maxScan is the largest request body that a router reads to find
+// references to session keys. Larger bodies and bodies of unknown length,
+// such as those of streamed multipart requests, are sent without being
+// read by the router, so they are routed by the keys in the request URL.
+const maxScan = 1 << 20
+
+const (
+	// keyTTL is the time a router remembers the server that
+	// issued a session key. OpenCPU servers remove sessions
+	// after 24 hours by default.
+	keyTTL = 24 * time.Hour
+
+	// maxKeys is the largest number of session keys that a
+	// router remembers. The oldest keys are forgotten first.
+	maxKeys = 1 << 16
+)
+
+// issue is the record of a session key issued by a server.
+type issue struct {
+	key string
+	b   *backend
+	at  time.Time
+}
+
+// router is an http.RoundTripper that distributes OpenCPU requests among
+// a set of servers that do not share session storage. The server that
+// issued each session key is recorded, and requests that refer to a key
+// in their path, query or body are sent to that server. Other requests
+// are sent to the servers in the order given by the order function,
+// moving on to the next server if a request cannot be sent or the
+// server refuses it as unavailable.
+//
+// Keys are remembered for keyTTL after they are issued, and only the
+// most recent maxKeys keys are remembered.
+type router struct {
+	// root is the API root of the servers.
+	root string
+
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	backends []*backend
+
+	// keys holds the issue of each known session
+	// key, and issues holds the issues in the
+	// order they were recorded.
+	keys   map[string]*issue
+	issues []*issue
+
+	// order returns the servers to try for a request
+	// that does not refer to a session key, in order
+	// of preference. It is called with mu held.
+	order func(backends []*backend) []*backend
+
+	// failed, if not nil, is called with mu held when
+	// a request to b fails with a transport error or
+	// the server refuses it as unavailable.
+	failed func(b *backend)
+}
+
+// newRoutedSession returns a session that sends its requests through r.
+func newRoutedSession(r *router) *Session {
+	return &Session{
+		host:   &url.URL{Scheme: "http", Host: "arrgh.invalid", Path: r.root},
+		root:   r.root,
+		client: &http.Client{Transport: r},
+	}
+}
+
+// server returns the API root URL of the server that holds the session
+// with the given key. For a session that routes its requests, this is the
+// server that issued the key, or the empty string if the key is not known.
+func (s *Session) server(key string) string {
+	if s.client == nil {
+		return s.host.String()
+	}
+	r, ok := s.client.Transport.(*router)
+	if !ok {
+		return s.host.String()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire(time.Now())
+	b := r.issuer(key)
+	if b == nil {
+		return ""
+	}
+	return b.base.String()
+}
+
+// keyPattern matches OpenCPU session keys.
+var keyPattern = regexp.MustCompile(`x[0-9a-f]{10,}`)
+
+// RoundTrip implements the http.RoundTripper interface.
+func (r *router) RoundTrip(req *http.Request) (*http.Response, error) {
+	var (
+		body       []byte
+		replayable = req.Body == nil || req.Body == http.NoBody
+	)
+	if !replayable && req.ContentLength > 0 && req.ContentLength <= maxScan {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		replayable = true
+	}
+
+	r.mu.Lock()
+	owner, err := r.owner(req, body)
+	var candidates []*backend
+	if owner != nil {
+		candidates = []*backend{owner}
+	} else if err == nil {
+		candidates = r.order(r.backends)
+		if len(candidates) == 0 {
+			err = errors.New("arrgh: no available servers")
+		}
+	}
+	r.mu.Unlock()
+	if err != nil {
+		if req.Body != nil && body == nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	for i, b := range candidates {
+		resp, err := r.send(b, req, body)
+		if err == nil && !unavailable(resp) {
+			return resp, nil
+		}
+		if r.failed != nil {
+			r.mu.Lock()
+			r.failed(b)
+			r.mu.Unlock()
+		}
+		if owner != nil || !replayable || !retryable(req, err) || i == len(candidates)-1 || req.Context().Err() != nil {
+			return resp, err
+		}
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+	panic("unreachable")
+}
+
+// unavailable returns whether resp indicates that the server refused
+// the request without handling it. Bad gateway and gateway timeout
+// responses are not included since the request may have been handled.
+func unavailable(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusServiceUnavailable
+}
+
+// retryable returns whether req may be sent to another server after
+// it failed with the transport error err, or the server was unavailable
+// if err is nil. Requests other than GET and HEAD are only retried if
+// they cannot have reached the server.
+func retryable(req *http.Request, err error) bool {
+	if err == nil || req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return true
+	}
+	var op *net.OpError
+	return errors.As(err, &op) && op.Op == "dial"
+}
+
+// owner returns the server that issued the session keys referred to by
+// the request with the given body, or nil if the request does not refer
+// to a known key. It is called with r.mu held.
+func (r *router) owner(req *http.Request, body []byte) (*backend, error) {
+	var (
+		owner *backend
+		key   string
+	)
+	r.expire(time.Now())
+	for _, src := range [][]byte{[]byte(req.URL.Path), []byte(req.URL.RawQuery), body} {
+		for _, k := range keyPattern.FindAll(src, -1) {
+			b := r.issuer(string(k))
+			if b == nil {
+				continue
+			}
+			if owner != nil && b != owner {
+				return nil, fmt.Errorf("arrgh: session keys %s and %s were issued by different servers", key, k)
+			}
+			owner, key = b, string(k)
+		}
+	}
+	return owner, nil
+}
+
+// issuer returns the server that issued key, or nil if the key is not
+// known. It is called with r.mu held.
+func (r *router) issuer(key string) *backend {
+	i, ok := r.keys[key]
+	if !ok {
+		return nil
+	}
+	return i.b
+}
+
+// record records b as the issuer of key. It is called with r.mu held.
+func (r *router) record(key string, b *backend) {
+	now := time.Now()
+	i := &issue{key: key, b: b, at: now}
+	r.keys[key] = i
+	r.issues = append(r.issues, i)
+	r.expire(now)
+}
+
+// expire forgets keys that were issued more than keyTTL before now, and
+// the oldest keys in excess of maxKeys. It is called with r.mu held.
+func (r *router) expire(now time.Time) {
+	var n int
+	for _, i := range r.issues {
+		if len(r.issues)-n <= maxKeys && now.Sub(i.at) < keyTTL {
+			break
+		}
+		if r.keys[i.key] == i {
+			delete(r.keys, i.key)
+		}
+		r.issues[n] = nil
+		n++
+	}
+	r.issues = r.issues[n:]
+}
+
+// forget forgets the keys issued by b. It is called with r.mu held.
+func (r *router) forget(b *backend) {
+	issues := r.issues[:0]
+	for _, i := range r.issues {
+		if i.b == b {
+			if r.keys[i.key] == i {
+				delete(r.keys, i.key)
+			}
+			continue
+		}
+		issues = append(issues, i)
+	}
+	for i := len(issues); i < len(r.issues); i++ {
+		r.issues[i] = nil
+	}
+	r.issues = issues
+}
+
+// send sends the request with the given body to b, recording the server
+// as the issuer of any session key in the response.
+func (r *router) send(b *backend, req *http.Request, body []byte) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	u := *b.base
+	u.Path = pth.Join(b.base.Path, strings.TrimPrefix(req.URL.Path, r.root))
+	if strings.HasSuffix(req.URL.Path, "/") {
+		u.Path += "/"
+	}
+	u.RawQuery = req.URL.RawQuery
+	out.URL = &u
+	out.Host = ""
+	if body != nil {
+		out.Body = ioutil.NopCloser(bytes.NewReader(body))
+		out.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	r.mu.Lock()
+	b.active++
+	r.mu.Unlock()
+	resp, err := r.transport.RoundTrip(out)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b.active--
+	if err != nil {
+		return nil, err
+	}
+	key := resp.Header.Get("X-Ocpu-Session")
+	if key == "" {
+		if loc, err := url.Parse(resp.Header.Get("Location")); err == nil {
+			key = keyOf(strings.TrimPrefix(strings.TrimPrefix(loc.Path, b.base.Path), "/"))
+		}
+	}
+	if key != "" {
+		r.record(key, b)
+	}
+	return resp, nil
+}
@@ -0,0 +1,126 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRouterUnknownLength(t *testing.T) {
+	var backends []*backend
+	for i := 0; i < 2; i++ {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			io.Copy(ioutil.Discard, req.Body)
+		}))
+		defer srv.Close()
+		u, err := url.Parse(srv.URL + "/ocpu")
+		if err != nil {
+			t.Fatalf("unexpected error parsing URL: %v", err)
+		}
+		backends = append(backends, &backend{base: u})
+	}
+	r := &router{
+		root:      "/ocpu",
+		transport: http.DefaultTransport,
+		backends:  backends,
+		keys:      make(map[string]*issue),
+		order:     func(b []*backend) []*backend { return b },
+	}
+	const key = "x0123456789"
+	r.record(key, backends[1])
+	s := newRoutedSession(r)
+
+	for _, test := range []struct {
+		name   string
+		path   string
+		params url.Values
+		body   io.Reader
+		want   *backend
+	}{
+		{
+			name: "known length",
+			path: "library/base/R/sum",
+			body: strings.NewReader("x=" + key),
+			want: backends[1],
+		},
+		{
+			// Bodies of unknown length are not read by
+			// the router, so the reference is not seen.
+			name: "unknown length",
+			path: "library/base/R/sum",
+			body: io.MultiReader(strings.NewReader("x=" + key)),
+			want: backends[0],
+		},
+		{
+			name:   "unknown length with key in query",
+			path:   "library/base/R/sum",
+			params: url.Values{"ref": {key}},
+			body:   io.MultiReader(strings.NewReader("x=" + key)),
+			want:   backends[1],
+		},
+		{
+			name: "unknown length with key in path",
+			path: "tmp/" + key + "/R",
+			body: io.MultiReader(strings.NewReader("x=1")),
+			want: backends[1],
+		},
+	} {
+		resp, err := s.Post(test.path, "application/x-www-form-urlencoded", test.params, test.body)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", test.name, err)
+		}
+		resp.Body.Close()
+		got := resp.Request.URL.Host
+		if want := test.want.base.Host; got != want {
+			t.Errorf("unexpected server for %s: got:%s want:%s", test.name, got, want)
+		}
+	}
+}
+
+func TestRouterExpire(t *testing.T) {
+	a := &backend{}
+	b := &backend{}
+	r := &router{keys: make(map[string]*issue)}
+
+	r.record("x0000000000", a)
+	r.record("x0000000001", b)
+	r.forget(a)
+	if r.issuer("x0000000000") != nil || r.issuer("x0000000001") != b || len(r.issues) != 1 {
+		t.Errorf("unexpected keys after forgetting server: %v", r.keys)
+	}
+
+	// Keys expire after keyTTL.
+	r.issues[0].at = time.Now().Add(-keyTTL)
+	r.record("x0000000002", a)
+	if r.issuer("x0000000001") != nil || r.issuer("x0000000002") != a || len(r.issues) != 1 {
+		t.Errorf("unexpected keys after expiry: %v", r.keys)
+	}
+
+	// Only maxKeys keys are remembered.
+	for i := 0; i < maxKeys; i++ {
+		r.record(fmt.Sprintf("x1%09x", i), b)
+	}
+	if len(r.keys) != maxKeys || len(r.issues) != maxKeys {
+		t.Errorf("unexpected number of keys: got:%d issues:%d want:%d", len(r.keys), len(r.issues), maxKeys)
+	}
+	if r.issuer("x0000000002") != nil || r.issuer("x1000000000") != b {
+		t.Error("oldest key not forgotten")
+	}
+
+	// Reissued keys are remembered from their latest issue.
+	r.record("x1000000000", a)
+	r.record("x2000000000", b)
+	if r.issuer("x1000000000") != a {
+		t.Error("reissued key forgotten")
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	pth "path"
+	"path/filepath"
+	"strings"
+)
+
+// scriptTypes lists the file extensions of the scripts that OpenCPU can run.
+var scriptTypes = map[string]bool{
+	".r":    true,
+	".rmd":  true,
+	".rnw":  true,
+	".brew": true,
+	".md":   true,
+	".tex":  true,
+}
+
+// artifactTypes maps the file extensions of rendered script outputs
+// to their MIME types.
+var artifactTypes = map[string]string{
+	".html": "text/html",
+	".pdf":  "application/pdf",
+	".md":   "text/markdown",
+	".tex":  "application/x-tex",
+}
+
+// ScriptResult is the result of running a script.
+type ScriptResult struct {
+	Result
+
+	// Artifacts holds the rendered documents
+	// created by the script.
+	Artifacts []Artifact
+}
+
+// Artifact is a rendered document in a session's working directory.
+type Artifact struct {
+	// Name is the name of the file in the
+	// session's working directory. It can be
+	// used with OpenFile to retrieve the artifact.
+	Name string
+
+	// Path is the path of the file relative
+	// to the OpenCPU root.
+	Path string
+
+	// Type is the MIME type of the file.
+	Type string
+}
+
+// RunScript runs the R script or document at the given OpenCPU path and returns
+// the session result and the rendered documents it created. The path may refer
+// to a file in a package, for example "library/mypkg/www/report.Rmd", or to a file
+// uploaded with UploadScript. The file must be an R script (.R), an R Markdown
+// (.Rmd), Sweave (.Rnw), brew (.brew), Markdown (.md) or LaTeX (.tex) document.
+// The arguments are passed to the script and are interpreted as R code.
+func (s *Session) RunScript(ctx context.Context, path string, args Params) (*ScriptResult, error) {
+	if !scriptTypes[strings.ToLower(pth.Ext(path))] {
+		return nil, fmt.Errorf("arrgh: unsupported script type: %q", path)
+	}
+	form := make(url.Values)
+	for k, v := range args {
+		form.Set(k, v)
+	}
+	res, err := s.Call(ctx, path, "application/x-www-form-urlencoded", nil, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	sr := ScriptResult{Result: *res}
+	files := sessionPath(res.Key, "files") + "/"
+	for _, p := range res.Paths {
+		if !strings.HasPrefix(p, files) {
+			continue
+		}
+		name := strings.TrimPrefix(p, files)
+		if name == pth.Base(path) {
+			// Don't report the script itself.
+			continue
+		}
+		typ, ok := artifactTypes[strings.ToLower(pth.Ext(name))]
+		if !ok {
+			continue
+		}
+		sr.Artifacts = append(sr.Artifacts, Artifact{Name: name, Path: p, Type: typ})
+	}
+	return &sr, nil
+}
+
+// UploadScript uploads the script f to a new session and returns the OpenCPU
+// path of the uploaded file for use with RunScript.
+func (s *Session) UploadScript(ctx context.Context, f NamedReader) (string, error) {
+	content, body, err := StreamMultipartParts(File("x", f, ""))
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	res, err := s.Call(ctx, "library/base/R/identity", content, nil, body)
+	if err != nil {
+		return "", err
+	}
+	return sessionPath(res.Key, "files", filepath.Base(f.Name())), nil
+}
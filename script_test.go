@@ -0,0 +1,76 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunScript(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ocpu/library/base/R/identity":
+			f, h, err := r.FormFile("x")
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			f.Close()
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, "/ocpu/tmp/x0000000001/R/.val\n/ocpu/tmp/x0000000001/files/%s\n", h.Filename)
+		case "/ocpu/tmp/x0000000001/files/report.Rmd":
+			if r.FormValue("n") != "10" {
+				http.Error(w, "missing argument", http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `/ocpu/tmp/x0000000002/console
+/ocpu/tmp/x0000000002/files/DESCRIPTION
+/ocpu/tmp/x0000000002/files/report.Rmd
+/ocpu/tmp/x0000000002/files/report.md
+/ocpu/tmp/x0000000002/files/report.html
+`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	s := testSession(t, srv.URL)
+	ctx := context.Background()
+
+	path, err := s.UploadScript(ctx, lenNamedReader{name: "report.Rmd", Reader: strings.NewReader("# Report\n")})
+	if err != nil {
+		t.Fatalf("unexpected error uploading script: %v", err)
+	}
+	if want := "tmp/x0000000001/files/report.Rmd"; path != want {
+		t.Errorf("unexpected script path: got:%q want:%q", path, want)
+	}
+
+	res, err := s.RunScript(ctx, path, Params{"n": "10"})
+	if err != nil {
+		t.Fatalf("unexpected error running script: %v", err)
+	}
+	if res.Key != "x0000000002" {
+		t.Errorf("unexpected key: got:%q want:%q", res.Key, "x0000000002")
+	}
+	want := []Artifact{
+		{Name: "report.md", Path: "tmp/x0000000002/files/report.md", Type: "text/markdown"},
+		{Name: "report.html", Path: "tmp/x0000000002/files/report.html", Type: "text/html"},
+	}
+	if !reflect.DeepEqual(res.Artifacts, want) {
+		t.Errorf("unexpected artifacts:\ngot: %+v\nwant:%+v", res.Artifacts, want)
+	}
+
+	_, err = s.RunScript(ctx, "library/mypkg/www/script.py", nil)
+	if err == nil {
+		t.Error("expected error for unsupported script type")
+	}
+}
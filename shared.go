@@ -0,0 +1,278 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// sharedServer is the state of a shared local OpenCPU server as recorded
+// in its state file.
+type sharedServer struct {
+	// PID is the process ID of the R process
+	// running the OpenCPU server.
+	PID int `json:"pid"`
+
+	// Host and Root are the OpenCPU server's
+	// URL and API root.
+	Host string `json:"host"`
+	Root string `json:"root"`
+}
+
+const (
+	sharedLock  = "lock"
+	sharedState = "server.json"
+	sharedLog   = "server.log"
+	sharedRefs  = "refs"
+)
+
+// sharedRef is a reference to a shared local server. A reference is a
+// file in the refs directory of the server's state directory that is
+// locked while the reference is held. Since the lock is released by the
+// operating system when the holding process exits, references held by
+// processes that have crashed are recognised however process IDs are
+// reused.
+type sharedRef struct {
+	dir    string
+	path   string
+	unlock func() error
+}
+
+// NewSharedLocalSession returns a session connected to a local OpenCPU server
+// shared by all processes using the same state directory, dir. If dir is empty,
+// the directory "arrgh-opencpu" in the system temporary directory is used, so
+// the server is shared by all processes on the machine.
+//
+// If no server is running, one is started as described for NewLocalSession on
+// an unused port and the server's logs are written to the file server.log in
+// dir. Otherwise the running server is used. Concurrent callers in different
+// processes are serialised by a lock file in dir, and each session holds a
+// reference to the server that is released by Close, or when the session is
+// garbage collected. The server is shut down when the last reference is
+// released. References held by processes that have exited are discarded.
+//
+// Shared sessions allow test binaries run in parallel by go test to share one
+// R instance rather than each starting R on a fixed port.
+func NewSharedLocalSession(dir, path, root string, timeout time.Duration) (*Session, error) {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "arrgh-opencpu")
+	}
+	return acquireShared(dir, func() (*Session, error) {
+		port, err := freePort()
+		if err != nil {
+			return nil, err
+		}
+		log, err := os.OpenFile(filepath.Join(dir, sharedLog), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		// The log file is retained by the R process, which
+		// may outlive this process.
+		defer log.Close()
+		return NewLocalSession(path, root, port, timeout, log)
+	})
+}
+
+// acquireShared returns a session holding a reference to the shared server
+// described by the state in dir, calling start to start a new server if no
+// server is running.
+func acquireShared(dir string, start func() (*Session, error)) (*Session, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := lockFile(filepath.Join(dir, sharedLock))
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	state, err := readShared(dir)
+	if err != nil {
+		return nil, err
+	}
+	if state != nil && processAlive(state.PID) && ping(state.Host) {
+		host, err := url.Parse(state.Host)
+		if err != nil {
+			return nil, err
+		}
+		ref, err := newSharedRef(dir)
+		if err != nil {
+			return nil, err
+		}
+		sess := &Session{host: host, root: state.Root, shared: ref}
+		runtime.SetFinalizer(sess, func(s *Session) { s.Close() })
+		return sess, nil
+	}
+
+	// Any recorded server is no longer usable, so make sure
+	// it is gone before starting a new one.
+	if state != nil && processAlive(state.PID) {
+		killProcess(state.PID)
+	}
+	sess, err := start()
+	if err != nil {
+		return nil, err
+	}
+	err = writeShared(dir, &sharedServer{
+		PID:  sess.cmd.Process.Pid,
+		Host: sess.host.String(),
+		Root: sess.root,
+	})
+	if err != nil {
+		sess.Close()
+		return nil, err
+	}
+	sess.shared, err = newSharedRef(dir)
+	if err != nil {
+		sess.Close()
+		os.Remove(filepath.Join(dir, sharedState))
+		return nil, err
+	}
+	runtime.SetFinalizer(sess, nil)
+	runtime.SetFinalizer(sess, func(s *Session) { s.Close() })
+	return sess, nil
+}
+
+// newSharedRef returns a new reference to the shared server in dir.
+// It is called with the state directory locked.
+func newSharedRef(dir string) (*sharedRef, error) {
+	refs := filepath.Join(dir, sharedRefs)
+	err := os.MkdirAll(refs, 0o755)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile(refs, fmt.Sprintf("%d-*.ref", os.Getpid()))
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	unlock, err := lockFile(f.Name())
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &sharedRef{dir: dir, path: f.Name(), unlock: unlock}, nil
+}
+
+// releaseShared releases the reference to a shared server, shutting the
+// server down if no references remain.
+func releaseShared(ref *sharedRef) error {
+	unlock, err := lockFile(filepath.Join(ref.dir, sharedLock))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	os.Remove(ref.path)
+	ref.unlock()
+	n, err := liveRefs(ref.dir)
+	if err != nil || n != 0 {
+		return err
+	}
+
+	state, err := readShared(ref.dir)
+	if err != nil || state == nil {
+		return err
+	}
+	err = os.Remove(filepath.Join(ref.dir, sharedState))
+	if processAlive(state.PID) {
+		kerr := killProcess(state.PID)
+		if err == nil {
+			err = kerr
+		}
+	}
+	return err
+}
+
+// liveRefs returns the number of references to the shared server in dir
+// that are still held, removing those that are not. It is called with the
+// state directory locked.
+func liveRefs(dir string) (int, error) {
+	refs, err := filepath.Glob(filepath.Join(dir, sharedRefs, "*.ref"))
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	for _, r := range refs {
+		held, err := lockHeld(r)
+		if err != nil {
+			return 0, err
+		}
+		if held {
+			n++
+		} else {
+			os.Remove(r)
+		}
+	}
+	return n, nil
+}
+
+// readShared returns the shared server state held in dir, or nil
+// if there is none.
+func readShared(dir string) (*sharedServer, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, sharedState))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var state sharedServer
+	err = json.Unmarshal(b, &state)
+	if err != nil {
+		// Treat a corrupt state file as absent; the
+		// server it described cannot be found.
+		return nil, nil
+	}
+	return &state, nil
+}
+
+// writeShared atomically writes the shared server state to dir.
+func writeShared(dir string, state *sharedServer) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, sharedState+".tmp")
+	err = ioutil.WriteFile(tmp, b, 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, sharedState))
+}
+
+// ping returns whether the OpenCPU server with the given API root URL is
+// responding successfully to requests for its info.
+func ping(root string) bool {
+	c := http.Client{Timeout: 5 * time.Second}
+	resp, err := c.Get(strings.TrimSuffix(root, "/") + "/info")
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return 200 <= resp.StatusCode && resp.StatusCode < 300
+}
+
+// freePort returns a TCP port that is not in use on the local host.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, fmt.Errorf("arrgh: no free port: %w", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,20 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package arrgh
+
+import (
+	"errors"
+	"runtime"
+)
+
+var errNoShared = errors.New("arrgh: shared local sessions are not supported on " + runtime.GOOS)
+
+func lockFile(path string) (unlock func() error, err error) { return nil, errNoShared }
+func lockHeld(path string) (bool, error)                    { return false, errNoShared }
+func processAlive(pid int) bool                             { return false }
+func killProcess(pid int) error                             { return errNoShared }
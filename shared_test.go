@@ -0,0 +1,219 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package arrgh
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShared(t *testing.T) {
+	dir := t.TempDir()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// The shared server is represented by a sleeping process
+	// so that its termination can be observed.
+	var (
+		starts int
+		cmd    *exec.Cmd
+	)
+	start := func() (*Session, error) {
+		starts++
+		cmd = exec.Command("sleep", "60")
+		err := cmd.Start()
+		if err != nil {
+			return nil, err
+		}
+		s := testSession(t, srv.URL)
+		s.cmd = cmd
+		return s, nil
+	}
+	done := make(chan error, 1)
+
+	first, err := acquireShared(dir, start)
+	if err != nil {
+		t.Fatalf("unexpected error acquiring first session: %v", err)
+	}
+	go func() { done <- cmd.Wait() }()
+	second, err := acquireShared(dir, start)
+	if err != nil {
+		t.Fatalf("unexpected error acquiring second session: %v", err)
+	}
+	if starts != 1 {
+		t.Errorf("unexpected number of server starts: got:%d want:1", starts)
+	}
+	if second.host.String() != first.host.String() {
+		t.Errorf("unexpected host for second session: got:%s want:%s", second.host, first.host)
+	}
+
+	// Add a reference from a process that has exited
+	// without releasing it; its file is not locked.
+	stale := filepath.Join(dir, sharedRefs, "1-stale.ref")
+	err = ioutil.WriteFile(stale, nil, 0o644)
+	if err != nil {
+		t.Fatalf("failed to write stale reference: %v", err)
+	}
+	n, err := liveRefs(dir)
+	if err != nil {
+		t.Fatalf("unexpected error counting references: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("unexpected number of live references: got:%d want:2", n)
+	}
+
+	err = first.Close()
+	if err != nil {
+		t.Errorf("unexpected error closing first session: %v", err)
+	}
+	if !processAlive(cmd.Process.Pid) {
+		t.Fatal("server terminated while referenced")
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale reference not removed: %v", err)
+	}
+	n, err = liveRefs(dir)
+	if err != nil {
+		t.Fatalf("unexpected error counting references: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("unexpected number of live references: got:%d want:1", n)
+	}
+
+	err = second.Close()
+	if err != nil {
+		t.Errorf("unexpected error closing second session: %v", err)
+	}
+	if err := <-done; err == nil {
+		t.Error("expected server to be killed")
+	}
+	state, err := readShared(dir)
+	if err != nil || state != nil {
+		t.Errorf("unexpected state after last release: %+v %v", state, err)
+	}
+
+	// A new server is started when the recorded server is gone.
+	third, err := acquireShared(dir, start)
+	if err != nil {
+		t.Fatalf("unexpected error acquiring third session: %v", err)
+	}
+	if starts != 2 {
+		t.Errorf("unexpected number of server starts: got:%d want:2", starts)
+	}
+	third.Close()
+	cmd.Wait()
+}
+
+func TestSharedUnhealthy(t *testing.T) {
+	dir := t.TempDir()
+	var healthy int32 = 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&healthy) == 0 {
+			http.Error(w, "broken", http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	var (
+		starts int
+		cmds   []*exec.Cmd
+	)
+	start := func() (*Session, error) {
+		starts++
+		cmd := exec.Command("sleep", "60")
+		err := cmd.Start()
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, cmd)
+		s := testSession(t, srv.URL)
+		s.cmd = cmd
+		return s, nil
+	}
+
+	first, err := acquireShared(dir, start)
+	if err != nil {
+		t.Fatalf("unexpected error acquiring first session: %v", err)
+	}
+	cmd := cmds[0]
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	// The server is up, but responds to requests
+	// for its info with an error.
+	atomic.StoreInt32(&healthy, 0)
+	second, err := acquireShared(dir, start)
+	if err != nil {
+		t.Fatalf("unexpected error acquiring second session: %v", err)
+	}
+	if starts != 2 {
+		t.Errorf("unexpected number of server starts: got:%d want:2", starts)
+	}
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Error("unhealthy server not shut down")
+	}
+	first.Close()
+	second.Close()
+	cmds[1].Wait()
+}
+
+func TestSharedFinalizer(t *testing.T) {
+	dir := t.TempDir()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	var cmd *exec.Cmd
+	start := func() (*Session, error) {
+		cmd = exec.Command("sleep", "60")
+		err := cmd.Start()
+		if err != nil {
+			return nil, err
+		}
+		s := testSession(t, srv.URL)
+		s.cmd = cmd
+		return s, nil
+	}
+
+	first, err := acquireShared(dir, start)
+	if err != nil {
+		t.Fatalf("unexpected error acquiring first session: %v", err)
+	}
+	defer func() {
+		first.Close()
+		cmd.Wait()
+	}()
+	// Leak a second session.
+	_, err = acquireShared(dir, start)
+	if err != nil {
+		t.Fatalf("unexpected error acquiring second session: %v", err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		runtime.GC()
+		n, err := liveRefs(dir)
+		if err != nil {
+			t.Fatalf("unexpected error counting references: %v", err)
+		}
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("leaked session reference not released: %d references", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// maxRestartDelay is the longest time a supervisor waits between
+// attempts to restart a server that cannot be started, unless its
+// RestartDelay is longer.
+const maxRestartDelay = time.Minute
+
+// Supervisor runs a local OpenCPU server, restarting R if it exits.
+type Supervisor struct {
+	// Path, Root, Port, Timeout and Log are
+	// passed to NewLocalSession to start the
+	// server.
+	Path    string
+	Root    string
+	Port    int
+	Timeout time.Duration
+	Log     io.Writer
+
+	// RestartDelay is the time to wait before
+	// restarting R after it exits. If it is
+	// zero, a delay of one second is used.
+	// The delay is doubled after each failed
+	// restart, up to one minute.
+	RestartDelay time.Duration
+
+	// ShutdownTimeout is the time R is given
+	// to exit when it is asked to terminate,
+	// before it is killed. If it is zero, a
+	// timeout of five seconds is used.
+	ShutdownTimeout time.Duration
+
+	// Started, if not nil, is called with the
+	// session each time the server starts.
+	Started func(*Session)
+
+	// Exited, if not nil, is called with the
+	// reason R exited when it exits while the
+	// supervisor is running.
+	Exited func(error)
+
+	// Failed, if not nil, is called with the
+	// error when the server cannot be
+	// restarted.
+	Failed func(error)
+
+	// start starts the server. It is used for testing.
+	start func(context.Context) (*Session, error)
+}
+
+// Run starts the server and supervises it until ctx is cancelled, when the
+// server is shut down and Run returns nil. If R exits while the server is
+// supervised, it is restarted after RestartDelay, and restarts that fail
+// are retried until ctx is cancelled. An error is returned if the server
+// cannot be started initially.
+//
+// The server is shut down by asking R to terminate and killing it if it
+// has not exited after ShutdownTimeout. On Windows, R is killed.
+func (sv *Supervisor) Run(ctx context.Context) error {
+	start := sv.start
+	if start == nil {
+		start = func(ctx context.Context) (*Session, error) {
+			return newLocalSession(ctx, sv.Path, sv.Root, sv.Port, sv.Timeout, sv.Log)
+		}
+	}
+	sess, err := start(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	for {
+		if sv.Started != nil {
+			sv.Started(sess)
+		}
+		exited := make(chan error, 1)
+		go func() { exited <- sess.cmd.Wait() }()
+
+		select {
+		case <-ctx.Done():
+			sv.stop(sess, exited)
+			return nil
+		case err = <-exited:
+			sess.Close()
+			if err == nil {
+				err = errors.New("arrgh: R exited")
+			}
+			if sv.Exited != nil {
+				sv.Exited(err)
+			}
+		}
+
+		sess = sv.restart(ctx, start)
+		if sess == nil {
+			return nil
+		}
+	}
+}
+
+// restart starts the server with start after R has exited, retrying with
+// increasing delays until the server starts. It returns nil if ctx is
+// cancelled first.
+func (sv *Supervisor) restart(ctx context.Context, start func(context.Context) (*Session, error)) *Session {
+	delay := sv.RestartDelay
+	if delay == 0 {
+		delay = time.Second
+	}
+	max := maxRestartDelay
+	if delay > max {
+		max = delay
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		sess, err := start(ctx)
+		if err == nil {
+			return sess
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if sv.Failed != nil {
+			sv.Failed(err)
+		}
+		delay *= 2
+		if delay > max {
+			delay = max
+		}
+	}
+}
+
+// stop shuts down the server of sess, asking R to terminate and killing
+// it if it has not exited within the shutdown timeout. exited receives
+// the result of waiting for R.
+func (sv *Supervisor) stop(sess *Session, exited <-chan error) {
+	grace := sv.ShutdownTimeout
+	if grace == 0 {
+		grace = 5 * time.Second
+	}
+	if sess.cmd.Process.Signal(stopSignal) == nil {
+		select {
+		case <-exited:
+			// Mark the session closed. The
+			// process has already exited.
+			sess.Close()
+			return
+		case <-time.After(grace):
+		}
+	}
+	sess.Close()
+	<-exited
+}
@@ -0,0 +1,14 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package arrgh
+
+import "os"
+
+// stopSignal is the signal sent to R to ask it to terminate. Sending
+// it is not supported on Windows, where R is killed instead.
+var stopSignal = os.Interrupt
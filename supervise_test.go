@@ -0,0 +1,191 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package arrgh
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSupervisor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan *Session)
+	var exits []error
+	sv := Supervisor{
+		RestartDelay: time.Millisecond,
+		Started:      func(s *Session) { started <- s },
+		Exited:       func(err error) { exits = append(exits, err) },
+		start: func(context.Context) (*Session, error) {
+			cmd := exec.Command("sleep", "60")
+			err := cmd.Start()
+			if err != nil {
+				return nil, err
+			}
+			s := testSession(t, "http://localhost")
+			s.cmd = cmd
+			return s, nil
+		},
+	}
+	done := make(chan error)
+	go func() { done <- sv.Run(ctx) }()
+
+	// Simulate R crashing.
+	first := <-started
+	err := first.cmd.Process.Kill()
+	if err != nil {
+		t.Fatalf("failed to kill process: %v", err)
+	}
+	second := <-started
+	if second == first {
+		t.Error("session not restarted")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error from Run: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("supervisor did not stop")
+	}
+	if second.cmd.ProcessState == nil {
+		t.Fatal("server still running after shutdown")
+	}
+	if sig := second.cmd.ProcessState.Sys().(syscall.WaitStatus).Signal(); sig != syscall.SIGTERM {
+		t.Errorf("unexpected shutdown signal: got:%v want:%v", sig, syscall.SIGTERM)
+	}
+	var exitErr *exec.ExitError
+	if len(exits) != 1 || !errors.As(exits[0], &exitErr) {
+		t.Errorf("unexpected exit reports: %v", exits)
+	}
+}
+
+func TestSupervisorStartError(t *testing.T) {
+	errFailed := errors.New("failed")
+	sv := Supervisor{start: func(context.Context) (*Session, error) { return nil, errFailed }}
+	err := sv.Run(context.Background())
+	if err != errFailed {
+		t.Errorf("unexpected error: got:%v want:%v", err, errFailed)
+	}
+}
+
+func TestSupervisorRestartError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errFailed := errors.New("failed")
+	var (
+		starts   int
+		failures []error
+	)
+	started := make(chan *Session)
+	sv := Supervisor{
+		RestartDelay: time.Millisecond,
+		Started:      func(s *Session) { started <- s },
+		Failed:       func(err error) { failures = append(failures, err) },
+		start: func(context.Context) (*Session, error) {
+			starts++
+			if starts == 2 || starts == 3 {
+				return nil, errFailed
+			}
+			cmd := exec.Command("sleep", "60")
+			err := cmd.Start()
+			if err != nil {
+				return nil, err
+			}
+			s := testSession(t, "http://localhost")
+			s.cmd = cmd
+			return s, nil
+		},
+	}
+	done := make(chan error)
+	go func() { done <- sv.Run(ctx) }()
+
+	first := <-started
+	err := first.cmd.Process.Kill()
+	if err != nil {
+		t.Fatalf("failed to kill process: %v", err)
+	}
+	select {
+	case second := <-started:
+		defer second.Close()
+	case err := <-done:
+		t.Fatalf("supervisor stopped after failed restart: %v", err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("session not restarted")
+	}
+	cancel()
+	<-done
+	if starts != 4 {
+		t.Errorf("unexpected number of starts: got:%d want:4", starts)
+	}
+	if len(failures) != 2 || failures[0] != errFailed || failures[1] != errFailed {
+		t.Errorf("unexpected failure reports: %v", failures)
+	}
+}
+
+func TestSupervisorStartCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sv := Supervisor{start: func(ctx context.Context) (*Session, error) {
+		cancel()
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+	err := sv.Run(ctx)
+	if err != nil {
+		t.Errorf("unexpected error: got:%v want:nil", err)
+	}
+}
+
+func TestSupervisorKill(t *testing.T) {
+	// R ignores the request to terminate.
+	cmd := exec.Command("sh", "-c", `trap "" TERM; echo ready; while :; do sleep 0.1; done`)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("failed to get stdout: %v", err)
+	}
+	err = cmd.Start()
+	if err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	_, err = bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to wait for process: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sv := Supervisor{
+		ShutdownTimeout: 100 * time.Millisecond,
+		Started:         func(*Session) { cancel() },
+		start: func(context.Context) (*Session, error) {
+			s := testSession(t, "http://localhost")
+			s.cmd = cmd
+			return s, nil
+		},
+	}
+	done := make(chan error)
+	go func() { done <- sv.Run(ctx) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error from Run: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("supervisor did not stop")
+	}
+	if sig := cmd.ProcessState.Sys().(syscall.WaitStatus).Signal(); sig != syscall.SIGKILL {
+		t.Errorf("unexpected shutdown signal: got:%v want:%v", sig, syscall.SIGKILL)
+	}
+}
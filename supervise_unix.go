@@ -0,0 +1,16 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package arrgh
+
+import (
+	"os"
+	"syscall"
+)
+
+// stopSignal is the signal sent to R to ask it to terminate.
+var stopSignal os.Signal = syscall.SIGTERM
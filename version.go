@@ -0,0 +1,348 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Capability is a set of OpenCPU server features that depend on the
+// version of OpenCPU or on the R packages installed on the server.
+type Capability uint
+
+const (
+	// CapWarnings indicates the server records the warnings
+	// and messages of a call in the session (OpenCPU ≥2.0.0).
+	CapWarnings Capability = 1 << iota
+
+	// CapApps indicates the server provides the apps
+	// endpoint (OpenCPU ≥2.0.0).
+	CapApps
+
+	// CapNDJSON indicates the server can encode objects
+	// as newline delimited JSON (OpenCPU ≥2.0.0).
+	CapNDJSON
+
+	// CapFeather indicates the server can encode objects
+	// in the feather format.
+	CapFeather
+
+	// CapParquet indicates the server can encode objects
+	// in the parquet format.
+	CapParquet
+)
+
+// capabilities lists each capability and either the OpenCPU version
+// that introduced it or the output format that provides it.
+//
+// Support for the feather and parquet formats depends on the R packages
+// that are installed alongside OpenCPU as well as its version, so these
+// capabilities are detected by requesting a dataset in the format rather
+// than inferred from the version.
+var capabilities = []struct {
+	cap    Capability
+	name   string
+	since  string
+	format Format
+}{
+	{cap: CapWarnings, name: "warnings", since: "2.0.0"},
+	{cap: CapApps, name: "apps", since: "2.0.0"},
+	{cap: CapNDJSON, name: "ndjson", since: "2.0.0"},
+	{cap: CapFeather, name: "feather", format: Feather},
+	{cap: CapParquet, name: "parquet", format: Parquet},
+}
+
+func (c Capability) String() string {
+	var names []string
+	for _, e := range capabilities {
+		if c&e.cap != 0 {
+			names = append(names, e.name)
+			c &^= e.cap
+		}
+	}
+	if c != 0 {
+		names = append(names, fmt.Sprintf("%#x", uint(c)))
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// UnsupportedError is returned when an operation requires a capability
+// the server does not have.
+type UnsupportedError struct {
+	// Capability is the missing capability.
+	Capability Capability
+
+	// Version is the version of the OpenCPU server.
+	Version string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("arrgh: %v unsupported by this server (OpenCPU %s)", e.Capability, e.Version)
+}
+
+// Version returns the version of the OpenCPU server. The version is
+// determined on first use and retained for the lifetime of the session.
+func (s *Session) Version(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	v := s.version
+	s.mu.Unlock()
+	if v != "" {
+		return v, nil
+	}
+
+	resp, err := s.GetContext(ctx, "/", nil)
+	if err != nil {
+		return "", err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	v = resp.Header.Get("X-Ocpu-Version")
+	if v == "" {
+		// Fall back to asking the installed package.
+		d, err := s.Description(ctx, Package{Library: SystemLibrary, Name: "opencpu"})
+		if err != nil {
+			return "", fmt.Errorf("arrgh: could not determine OpenCPU version: %w", err)
+		}
+		v = d.Version
+	}
+	if _, err := parseVersion(v); err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.version = v
+	s.mu.Unlock()
+	return v, nil
+}
+
+// Capabilities returns the set of capabilities of the OpenCPU server.
+// Capabilities that cannot be inferred from the server's version are
+// detected on first use and retained for the lifetime of the session.
+func (s *Session) Capabilities(ctx context.Context) (Capability, error) {
+	return s.capabilities(ctx, ^Capability(0))
+}
+
+// Supports returns whether the server has all the capabilities in c.
+func (s *Session) Supports(ctx context.Context, c Capability) (bool, error) {
+	caps, err := s.capabilities(ctx, c)
+	if err != nil {
+		return false, err
+	}
+	return caps&c == c, nil
+}
+
+// require returns an *UnsupportedError if the server does not have
+// all the capabilities in c.
+func (s *Session) require(ctx context.Context, c Capability) error {
+	caps, err := s.capabilities(ctx, c)
+	if err != nil {
+		return err
+	}
+	if missing := c &^ caps; missing != 0 {
+		v, _ := s.Version(ctx)
+		return &UnsupportedError{Capability: missing, Version: v}
+	}
+	return nil
+}
+
+// capabilities returns the capabilities of the server, detecting those
+// in want that are provided by an output format and have not already
+// been detected.
+func (s *Session) capabilities(ctx context.Context, want Capability) (Capability, error) {
+	v, err := s.Version(ctx)
+	if err != nil {
+		return 0, err
+	}
+	caps, err := capabilitiesOf(v)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range capabilities {
+		if e.format == "" || want&e.cap == 0 {
+			continue
+		}
+		s.mu.Lock()
+		detected, ok := s.formats[e.cap]
+		s.mu.Unlock()
+		if !ok {
+			detected, err = s.detectFormat(ctx, e.format)
+			if err != nil {
+				return 0, err
+			}
+			s.mu.Lock()
+			if s.formats == nil {
+				s.formats = make(map[Capability]bool)
+			}
+			s.formats[e.cap] = detected
+			s.mu.Unlock()
+		}
+		if detected {
+			caps |= e.cap
+		}
+	}
+	return caps, nil
+}
+
+// detectFormat returns whether the server can encode objects in the given
+// format by requesting the cars dataset, which is always installed with R,
+// in that format. The server responds with a bad request status for formats
+// it does not support.
+func (s *Session) detectFormat(ctx context.Context, f Format) (bool, error) {
+	r, err := s.open(ctx, Package{Library: SystemLibrary, Name: "datasets"}.path("data", "cars", string(f)), nil)
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) && e.StatusCode == http.StatusBadRequest {
+			return false, nil
+		}
+		return false, err
+	}
+	r.Close()
+	return true, nil
+}
+
+// capabilitiesOf returns the capabilities implied by the given OpenCPU version.
+func capabilitiesOf(version string) (Capability, error) {
+	v, err := parseVersion(version)
+	if err != nil {
+		return 0, err
+	}
+	var caps Capability
+	for _, e := range capabilities {
+		if e.since == "" {
+			continue
+		}
+		since, _ := parseVersion(e.since)
+		if compareVersions(v, since) >= 0 {
+			caps |= e.cap
+		}
+	}
+	return caps, nil
+}
+
+// parseVersion parses an R package version string such as "2.0.3.1"
+// or "0.20-35".
+func parseVersion(s string) ([]int, error) {
+	f := strings.FieldsFunc(s, func(r rune) bool { return r == '.' || r == '-' })
+	if len(f) == 0 {
+		return nil, fmt.Errorf("arrgh: invalid version: %q", s)
+	}
+	v := make([]int, len(f))
+	for i, e := range f {
+		n, err := strconv.Atoi(e)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("arrgh: invalid version: %q", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// compareVersions returns -1, 0 or 1 depending on whether
+// a is less than, equal to or greater than b.
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Format is an OpenCPU object output format.
+type Format string
+
+const (
+	JSON     Format = "json"
+	CSV      Format = "csv"
+	TSV      Format = "tab"
+	NDJSON   Format = "ndjson"
+	RDS      Format = "rds"
+	RDA      Format = "rda"
+	Protobuf Format = "pb"
+	Feather  Format = "feather"
+	Parquet  Format = "parquet"
+	Text     Format = "text"
+	Print    Format = "print"
+)
+
+// capability returns the capability required to use the format.
+func (f Format) capability() Capability {
+	switch f {
+	case NDJSON:
+		return CapNDJSON
+	case Feather:
+		return CapFeather
+	case Parquet:
+		return CapParquet
+	}
+	return 0
+}
+
+// Object returns a reader for the R object at the given OpenCPU path encoded
+// in the specified format. The path is relative to the OpenCPU root, for example
+// "tmp/x0113a3ca85/R/.val" or "library/datasets/R/cars". The URL parameters are
+// interpreted by the format's encoder. If the server does not support the format,
+// an *UnsupportedError is returned. The returned reader must be closed after use.
+func (s *Session) Object(ctx context.Context, path string, format Format, params url.Values) (io.ReadCloser, error) {
+	if c := format.capability(); c != 0 {
+		err := s.require(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.open(ctx, path+"/"+string(format), params)
+}
+
+// Warnings returns the warnings raised during the call that created the
+// session with the given key.
+func (s *Session) Warnings(ctx context.Context, key string) ([]string, error) {
+	return s.conditions(ctx, key, "warnings")
+}
+
+// Messages returns the messages emitted during the call that created the
+// session with the given key.
+func (s *Session) Messages(ctx context.Context, key string) ([]string, error) {
+	return s.conditions(ctx, key, "messages")
+}
+
+func (s *Session) conditions(ctx context.Context, key, kind string) ([]string, error) {
+	err := s.require(ctx, CapWarnings)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.open(ctx, sessionPath(key, kind), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var lines []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if l := strings.TrimSpace(sc.Text()); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines, sc.Err()
+}
@@ -0,0 +1,138 @@
+// Copyright ©2026 Dan Kortschak. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrgh
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCapabilitiesOf(t *testing.T) {
+	for _, test := range []struct {
+		version string
+		want    Capability
+	}{
+		{version: "1.6.2", want: 0},
+		{version: "2.0.3.1", want: CapWarnings | CapApps | CapNDJSON},
+		{version: "2.2.9", want: CapWarnings | CapApps | CapNDJSON},
+	} {
+		got, err := capabilitiesOf(test.version)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", test.version, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("unexpected capabilities for %q: got:%v want:%v", test.version, got, test.want)
+		}
+	}
+	_, err := capabilitiesOf("latest")
+	if err == nil {
+		t.Error("expected error for invalid version")
+	}
+}
+
+func versionServer(header, description string, probes *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header != "" {
+			w.Header().Set("X-Ocpu-Version", header)
+		}
+		switch r.URL.Path {
+		case "/ocpu/":
+			w.Write([]byte("OpenCPU"))
+		case "/ocpu/library/opencpu/DESCRIPTION":
+			w.Write([]byte(description))
+		case "/ocpu/tmp/x0123456789/warnings":
+			w.Write([]byte("first warning\nsecond warning\n"))
+		case "/ocpu/tmp/x0123456789/R/.val/ndjson":
+			w.Write([]byte("{}\n"))
+		case "/ocpu/library/datasets/data/cars/feather":
+			*probes++
+			w.Write([]byte("FEA1"))
+		case "/ocpu/library/datasets/data/cars/parquet":
+			*probes++
+			http.Error(w, "unsupported output format", http.StatusBadRequest)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestVersion(t *testing.T) {
+	ctx := context.Background()
+
+	var probes int
+	srv := versionServer("2.0.3.1", "", &probes)
+	defer srv.Close()
+	s := testSession(t, srv.URL)
+	v, err := s.Version(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != "2.0.3.1" {
+		t.Errorf("unexpected version: got:%q want:%q", v, "2.0.3.1")
+	}
+	warnings, err := s.Warnings(ctx, "x0123456789")
+	if err != nil {
+		t.Fatalf("unexpected error getting warnings: %v", err)
+	}
+	if want := []string{"first warning", "second warning"}; !reflect.DeepEqual(warnings, want) {
+		t.Errorf("unexpected warnings: got:%q want:%q", warnings, want)
+	}
+	r, err := s.Object(ctx, "tmp/x0123456789/R/.val", NDJSON, nil)
+	if err != nil {
+		t.Fatalf("unexpected error getting object: %v", err)
+	}
+	b, _ := ioutil.ReadAll(r)
+	r.Close()
+	if string(b) != "{}\n" {
+		t.Errorf("unexpected object: got:%q", b)
+	}
+	ok, err := s.Supports(ctx, CapParquet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Error("unexpected parquet support")
+	}
+	_, err = s.Object(ctx, "tmp/x0123456789/R/.val", Parquet, nil)
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) || unsupported.Capability != CapParquet {
+		t.Errorf("expected unsupported parquet error: got:%v", err)
+	}
+
+	caps, err := s.Capabilities(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := CapWarnings | CapApps | CapNDJSON | CapFeather; caps != want {
+		t.Errorf("unexpected capabilities: got:%v want:%v", caps, want)
+	}
+	if probes != 2 {
+		t.Errorf("unexpected number of format probes: got:%d want:2", probes)
+	}
+
+	old := versionServer("", "Package: opencpu\nVersion: 1.6.2\n", &probes)
+	defer old.Close()
+	s = testSession(t, old.URL)
+	v, err = s.Version(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != "1.6.2" {
+		t.Errorf("unexpected version: got:%q want:%q", v, "1.6.2")
+	}
+	_, err = s.Warnings(ctx, "x0123456789")
+	if !errors.As(err, &unsupported) || unsupported.Capability != CapWarnings {
+		t.Errorf("expected unsupported warnings error: got:%v", err)
+	}
+	if want := "arrgh: warnings unsupported by this server (OpenCPU 1.6.2)"; err.Error() != want {
+		t.Errorf("unexpected error message: got:%q want:%q", err, want)
+	}
+}